@@ -2,7 +2,6 @@ package ads1x15
 
 import (
 	"sync"
-	"time"
 
 	"github.com/reef-pi/hal"
 )
@@ -25,6 +24,7 @@ func Ads1115Factory() hal.DriverFactory {
 					Description:  "Supports ADS1115 ADC",
 					Capabilities: []hal.Capability{hal.AnalogInput},
 				},
+				dataRates: ads1115DataRates,
 			},
 		}
 
@@ -35,5 +35,5 @@ func Ads1115Factory() hal.DriverFactory {
 }
 
 func (f *ads1115Factory) NewDriver(parameters map[string]interface{}, hardwareResources interface{}) (hal.Driver, error) {
-	return f.newDriver(parameters, hardwareResources, 0, 9*time.Millisecond)
+	return f.newDriver(parameters, hardwareResources, 0)
 }
@@ -0,0 +1,70 @@
+package ads1x15
+
+import "math"
+
+// gainAuto selects the gain of a channel from its readings. Auto ranging
+// channels return millivolts instead of raw counts, and only support single
+// shot mode.
+const gainAuto = "auto"
+
+// gainSteps lists the gain settings from the coarsest to the finest, with
+// their full scale range in millivolts.
+var gainSteps = []struct {
+	config uint16
+	fsr    float64
+}{
+	{configGainTwoThirds, 6144},
+	{configGainOne, 4096},
+	{configGainTwo, 2048},
+	{configGainFour, 1024},
+	{configGainEight, 512},
+	{configGainSixteen, 256},
+}
+
+// Auto ranging steps down when a reading is within 2% of full scale and steps
+// up while it would stay below 90% of full scale at the next gain.
+const (
+	saturationRatio = 0.98
+	stepUpRatio     = 0.45
+)
+
+func gainStep(config uint16) int {
+	for i, s := range gainSteps {
+		if s.config == config {
+			return i
+		}
+	}
+	return 0
+}
+
+// autoConvert performs conversions until the reading sits comfortably within
+// the range of the current gain, and returns it in millivolts. The selected
+// gain is kept as the starting point for the next reading.
+func (c *channel) autoConvert() (float64, error) {
+	full := float64(int(0x7FFF) >> c.shift)
+	step := gainStep(c.gainConfig)
+	var v float64
+	for range gainSteps {
+		c.gainConfig = gainSteps[step].config
+		raw, err := c.convert()
+		if err != nil {
+			return raw, err
+		}
+		v = raw
+		a := math.Abs(raw)
+		switch {
+		case a >= full*saturationRatio && step > 0:
+			step--
+		case a < full*stepUpRatio && step < len(gainSteps)-1:
+			step++
+		default:
+			return c.millivolts(v), nil
+		}
+	}
+	return c.millivolts(v), nil
+}
+
+// millivolts converts a raw reading at the current gain
+func (c *channel) millivolts(raw float64) float64 {
+	return raw * gainSteps[gainStep(c.gainConfig)].fsr / float64(int(0x8000)>>c.shift)
+}
@@ -0,0 +1,97 @@
+package ads1x15
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// adcBus simulates an ADS1x15 measuring a fixed input voltage, returning
+// conversions that depend on the gain in the config register.
+type adcBus struct {
+	*regBus
+	mv    float64
+	shift int
+	gains []uint16
+}
+
+func (b *adcBus) ReadFromReg(addr, reg byte, value []byte) error {
+	if reg == conversionRegister {
+		gain := b.regs[configRegister] & 0x0E00
+		b.gains = append(b.gains, gain)
+		raw := math.Round(b.mv / gainSteps[gainStep(gain)].fsr * 32768)
+		raw = math.Max(math.Min(raw, 0x7FFF), -0x8000)
+		b.regs[reg] = uint16(int16(raw)>>b.shift) << b.shift
+	}
+	return b.regBus.ReadFromReg(addr, reg, value)
+}
+
+func TestAutoGain(t *testing.T) {
+	bus := &adcBus{regBus: newRegBus(), mv: 300}
+	f := Ads1115Factory()
+	p := map[string]interface{}{
+		"Address": 72,
+		"Gain 1":  "auto",
+		"Gain 2":  "1",
+		"Gain 3":  "2",
+		"Gain 4":  4,
+	}
+	if valid, failures := f.ValidateParameters(p); !valid {
+		t.Fatal(failures)
+	}
+	d, err := f.NewDriver(p, bus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch, err := d.(*driver).AnalogInputPin(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := ch.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(v-300) > 0.1 {
+		t.Error("expected 300mV, got", v)
+	}
+	if g := bus.gains[len(bus.gains)-1]; g != configGainEight {
+		t.Errorf("expected gain 8 for 300mV, got %#04x", g)
+	}
+
+	// The next reading starts at the selected gain and steps down when the
+	// input saturates it.
+	bus.gains = nil
+	bus.mv = 3000
+	if v, err = ch.Value(); err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(v-3000) > 0.5 {
+		t.Error("expected 3000mV, got", v)
+	}
+	if bus.gains[0] != configGainEight || bus.gains[len(bus.gains)-1] != configGainOne {
+		t.Errorf("expected to step from gain 8 down to gain 1, got %#04x", bus.gains)
+	}
+
+	// The gain can not follow the input in continuous mode
+	if err := ch.(Channel).StartContinuous(); !errors.Is(err, ErrAutoGain) {
+		t.Error("expected continuous mode to be refused on an auto gain channel, got", err)
+	}
+	if err := ch.(Channel).SetComparator(ComparatorConfig{High: 1000}); !errors.Is(err, ErrAutoGain) {
+		t.Error("expected comparator to be refused on an auto gain channel, got", err)
+	}
+
+	// Fixed gain channels keep returning raw counts
+	ch, _ = d.(*driver).AnalogInputPin(1)
+	if v, _ = ch.Value(); v != 24000 {
+		t.Error("expected raw count 24000, got", v)
+	}
+}
+
+func TestAutoGainInvalid(t *testing.T) {
+	if _, err := parseGain("automatic"); err == nil {
+		t.Error("unknown gain should be rejected")
+	}
+	if g, err := parseGain(gainAuto); err != nil || g != gainAuto {
+		t.Error("auto gain should be accepted", err)
+	}
+}
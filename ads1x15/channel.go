@@ -10,38 +10,67 @@ import (
 	"github.com/reef-pi/rpi/i2c"
 )
 
+type channelConfig struct {
+	number     int
+	name       string
+	pinAddress uint16
+	gain       uint16
+	dataRate   uint16
+	delay      time.Duration
+	samples    int
+	filter     string
+	shift      int
+	autoGain   bool
+	chip       *chip
+}
+
 type channel struct {
 	bus        i2c.Bus
 	address    byte
 	pinAddress uint16
 	channel    int
+	name       string
 	gainConfig uint16
+	dataRate   uint16
 	calibrator hal.Calibrator
 	shift      int
 	delay      time.Duration
+	samples    int
+	filter     string
+	autoGain   bool
+	chip       *chip
 }
 
-func newChannel(b i2c.Bus, address byte, channelNum int, pinAddress uint16, gain uint16, shift int, delay time.Duration) (*channel, error) {
+func newChannel(b i2c.Bus, address byte, cfg channelConfig) (*channel, error) {
 	c, err := hal.CalibratorFactory([]hal.Measurement{})
 	if err != nil {
 		return nil, err
 	}
+	if cfg.chip == nil {
+		cfg.chip = new(chip)
+	}
 
 	return &channel{
 		bus:        b,
 		address:    address,
-		pinAddress: pinAddress,
-		channel:    channelNum,
-		gainConfig: gain,
+		pinAddress: cfg.pinAddress,
+		channel:    cfg.number,
+		name:       cfg.name,
+		gainConfig: cfg.gain,
+		dataRate:   cfg.dataRate,
 		calibrator: c,
-		shift:      shift,
-		delay:      delay,
+		shift:      cfg.shift,
+		delay:      cfg.delay,
+		samples:    cfg.samples,
+		filter:     cfg.filter,
+		autoGain:   cfg.autoGain,
+		chip:       cfg.chip,
 	}, nil
 
 }
 
 func (c *channel) Name() string {
-	return fmt.Sprintf("%d", c.channel)
+	return c.name
 }
 
 func (c *channel) Number() int {
@@ -57,11 +86,41 @@ func (c *channel) Calibrate(points []hal.Measurement) error {
 	return nil
 }
 
+// Value returns the reading of the channel, combining c.samples conversions
+// with the configured filter.
 func (c *channel) Value() (float64, error) {
+	c.chip.Lock()
+	defer c.chip.Unlock()
+	read := c.convert
+	if c.autoGain {
+		read = c.autoConvert
+	}
+	switch c.chip.active {
+	case nil:
+	case c:
+		read = c.readConversion
+	default:
+		return 0, fmt.Errorf("%w: channel %s is running", ErrContinuous, c.chip.active.name)
+	}
+	vs := make([]float64, 0, c.samples)
+	for i := 0; i < c.samples; i++ {
+		if i > 0 && c.chip.active == c {
+			time.Sleep(c.delay)
+		}
+		v, err := read()
+		if err != nil {
+			return v, err
+		}
+		vs = append(vs, v)
+	}
+	return reduce(vs, c.filter), nil
+}
+
+func (c *channel) convert() (float64, error) {
 
 	var config uint16 = configOsSingle |
 		configModeSingle |
-		configDataRate1600 |
+		c.dataRate |
 		configComparatorModeTraditional |
 		configComparitorNonLatching |
 		configComparitorPolarityActiveLow |
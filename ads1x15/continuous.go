@@ -0,0 +1,193 @@
+package ads1x15
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/reef-pi/hal"
+)
+
+const (
+	conversionRegister = 0x00
+	configRegister     = 0x01
+	loThreshRegister   = 0x02
+	hiThreshRegister   = 0x03
+)
+
+// ErrContinuous is returned when a channel is read while another channel of
+// the same chip is running in continuous mode.
+var ErrContinuous = errors.New("ADS1X15 is in continuous mode")
+
+// ErrAutoGain is returned when an auto ranging channel is asked to run in
+// continuous mode.
+var ErrAutoGain = errors.New("ADS1X15 auto gain channels only support single shot mode")
+
+// chip holds the state shared by all channels of a single ADS1x15. The
+// multiplexer can only follow one input at a time, so at most one channel
+// can be running in continuous mode.
+type chip struct {
+	sync.Mutex
+	active *channel
+}
+
+// ComparatorConfig describes the hardware comparator driving the ALERT/RDY
+// pin. Thresholds are raw readings, as returned by Value.
+type ComparatorConfig struct {
+	// Low and High are the lower and upper thresholds.
+	Low, High int16
+	// Window asserts ALERT/RDY when the reading leaves [Low, High] instead
+	// of using High as a trip point and Low as the reset point.
+	Window bool
+	// ActiveHigh drives ALERT/RDY high when asserted.
+	ActiveHigh bool
+	// Latching keeps ALERT/RDY asserted until the conversion register is read.
+	Latching bool
+	// Queue is the number of consecutive conversions beyond the thresholds
+	// before ALERT/RDY is asserted: 1, 2 or 4. Zero means 1.
+	Queue int
+}
+
+// Channel is an analog input of an ADS1x15 that can additionally run in
+// continuous mode and program the hardware comparator. Auto ranging channels
+// can not: the chip converts at a fixed gain in continuous mode, so the
+// readings could not be re-ranged.
+type Channel interface {
+	hal.AnalogInputPin
+	// StartContinuous switches the chip to continuous conversion of this
+	// channel. Value then reads the latest conversion without waiting.
+	StartContinuous() error
+	// StopContinuous returns the chip to single-shot mode and disables the
+	// comparator.
+	StopContinuous() error
+	// EnableConversionReady starts continuous mode with ALERT/RDY pulsing
+	// after every conversion.
+	EnableConversionReady() error
+	// SetComparator starts continuous mode with the comparator programmed
+	// so ALERT/RDY can act as a hardware cutoff.
+	SetComparator(ComparatorConfig) error
+}
+
+func (c *channel) StartContinuous() error {
+	return c.continuous(configComparitorQueueNone)
+}
+
+func (c *channel) EnableConversionReady() error {
+	// A high threshold with MSB set and a low threshold with MSB cleared
+	// turn ALERT/RDY into a conversion ready signal.
+	return c.continuous(configComparatorModeTraditional|configComparitorQueue1, 0x0000, 0x8000)
+}
+
+func (c *channel) SetComparator(cfg ComparatorConfig) error {
+	if c.autoGain {
+		return ErrAutoGain
+	}
+	if cfg.Low > cfg.High {
+		return fmt.Errorf("low threshold %d is above high threshold %d", cfg.Low, cfg.High)
+	}
+	var comp uint16
+	switch cfg.Queue {
+	case 0, 1:
+		comp = configComparitorQueue1
+	case 2:
+		comp = configComparitorQueue2
+	case 4:
+		comp = configComparitorQueue4
+	default:
+		return fmt.Errorf("comparator queue must be 1, 2 or 4. %d was received", cfg.Queue)
+	}
+	if cfg.Window {
+		comp |= configComparatorModeWindow
+	}
+	if cfg.ActiveHigh {
+		comp |= configComparitorPolarityActiveHigh
+	}
+	if cfg.Latching {
+		comp |= configComparitorLatching
+	}
+	lo, err := c.threshold(cfg.Low)
+	if err != nil {
+		return err
+	}
+	hi, err := c.threshold(cfg.High)
+	if err != nil {
+		return err
+	}
+	return c.continuous(comp, lo, hi)
+}
+
+func (c *channel) StopContinuous() error {
+	c.chip.Lock()
+	defer c.chip.Unlock()
+	if c.chip.active != c {
+		return nil
+	}
+	config := configModeSingle |
+		c.dataRate |
+		configComparitorQueueNone |
+		c.pinAddress |
+		c.gainConfig
+	if err := c.writeRegister(configRegister, config); err != nil {
+		return err
+	}
+	c.chip.active = nil
+	return nil
+}
+
+// continuous switches the chip to continuous conversion of c, writing the
+// low and high threshold registers first when given.
+func (c *channel) continuous(comparator uint16, thresholds ...uint16) error {
+	if c.autoGain {
+		return ErrAutoGain
+	}
+	c.chip.Lock()
+	defer c.chip.Unlock()
+	if c.chip.active != nil && c.chip.active != c {
+		return fmt.Errorf("%w: channel %s is running", ErrContinuous, c.chip.active.name)
+	}
+	if len(thresholds) == 2 {
+		if err := c.writeRegister(loThreshRegister, thresholds[0]); err != nil {
+			return err
+		}
+		if err := c.writeRegister(hiThreshRegister, thresholds[1]); err != nil {
+			return err
+		}
+	}
+	config := configModeContinuous |
+		c.dataRate |
+		comparator |
+		c.pinAddress |
+		c.gainConfig
+	if err := c.writeRegister(configRegister, config); err != nil {
+		return err
+	}
+	c.chip.active = c
+	return nil
+}
+
+// threshold converts a reading to its register value. The ADS1015 only uses
+// the upper 12 bits of the threshold registers.
+func (c *channel) threshold(v int16) (uint16, error) {
+	raw := float64(v)
+	max := float64(int(0x7FFF) >> c.shift)
+	min := -max - 1
+	if raw > max || raw < min {
+		return 0, fmt.Errorf("threshold %d is out of range [%v, %v]", v, min, max)
+	}
+	return uint16(int16(raw) << c.shift), nil
+}
+
+func (c *channel) writeRegister(reg byte, v uint16) error {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, v)
+	return c.bus.WriteToReg(c.address, reg, b)
+}
+
+func (c *channel) readConversion() (float64, error) {
+	b := make([]byte, 2)
+	if err := c.bus.ReadFromReg(c.address, conversionRegister, b); err != nil {
+		return 0, err
+	}
+	return float64(int16(binary.BigEndian.Uint16(b)) >> c.shift), nil
+}
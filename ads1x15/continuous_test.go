@@ -0,0 +1,126 @@
+package ads1x15
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/reef-pi/hal"
+)
+
+// regBus simulates the ADS1x15 register map.
+type regBus struct {
+	regs   map[byte]uint16
+	writes []byte
+}
+
+func newRegBus() *regBus { return &regBus{regs: map[byte]uint16{}} }
+
+func (b *regBus) SetAddress(_ byte) error                      { return nil }
+func (b *regBus) ReadBytes(addr byte, num int) ([]byte, error) { return nil, nil }
+func (b *regBus) WriteBytes(addr byte, value []byte) error     { return nil }
+func (b *regBus) ReadFromReg(addr, reg byte, value []byte) error {
+	v := b.regs[reg]
+	value[0], value[1] = byte(v>>8), byte(v)
+	return nil
+}
+func (b *regBus) WriteToReg(addr, reg byte, value []byte) error {
+	b.writes = append(b.writes, reg)
+	b.regs[reg] = uint16(value[0])<<8 | uint16(value[1])
+	return nil
+}
+func (b *regBus) Close() error { return nil }
+
+func continuousChannels(t *testing.T, bus *regBus, f hal.DriverFactory) (Channel, Channel) {
+	d, err := f.NewDriver(params, bus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pins := d.(*driver).AnalogInputPins()
+	return pins[0].(Channel), pins[1].(Channel)
+}
+
+func TestContinuousMode(t *testing.T) {
+	bus := newRegBus()
+	ch0, ch1 := continuousChannels(t, bus, Ads1115Factory())
+
+	if err := ch0.StartContinuous(); err != nil {
+		t.Fatal(err)
+	}
+	if c := bus.regs[configRegister]; c != 0x4083 {
+		t.Errorf("expected continuous config 0x4083, got %#04x", c)
+	}
+	bus.regs[conversionRegister] = 0x1234
+	bus.writes = nil
+	v, err := ch0.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != 0x1234 {
+		t.Error("expected 4660, got", v)
+	}
+	if len(bus.writes) != 0 {
+		t.Error("continuous reads should not write to the chip:", bus.writes)
+	}
+
+	if _, err := ch1.Value(); !errors.Is(err, ErrContinuous) {
+		t.Error("expected ErrContinuous reading another channel, got", err)
+	}
+	if err := ch1.StartContinuous(); !errors.Is(err, ErrContinuous) {
+		t.Error("expected ErrContinuous starting another channel, got", err)
+	}
+
+	if err := ch0.StopContinuous(); err != nil {
+		t.Fatal(err)
+	}
+	if c := bus.regs[configRegister]; c != 0x4183 {
+		t.Errorf("expected single-shot config 0x4183, got %#04x", c)
+	}
+	if err := ch1.StartContinuous(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConversionReady(t *testing.T) {
+	bus := newRegBus()
+	ch, _ := continuousChannels(t, bus, Ads1115Factory())
+	if err := ch.EnableConversionReady(); err != nil {
+		t.Fatal(err)
+	}
+	if bus.regs[loThreshRegister] != 0x0000 || bus.regs[hiThreshRegister] != 0x8000 {
+		t.Errorf("unexpected thresholds lo=%#04x hi=%#04x", bus.regs[loThreshRegister], bus.regs[hiThreshRegister])
+	}
+	if c := bus.regs[configRegister]; c != 0x4080 {
+		t.Errorf("expected config 0x4080, got %#04x", c)
+	}
+	if bus.writes[len(bus.writes)-1] != configRegister {
+		t.Error("config should be written after the thresholds")
+	}
+}
+
+func TestComparator(t *testing.T) {
+	bus := newRegBus()
+	ch, _ := continuousChannels(t, bus, Ads1015Factory())
+	err := ch.SetComparator(ComparatorConfig{Low: -100, High: 1500, Window: true, ActiveHigh: true, Latching: true, Queue: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lo := bus.regs[loThreshRegister]; lo != uint16(0xF9C0) {
+		t.Errorf("expected low threshold 0xf9c0, got %#04x", lo)
+	}
+	if hi := bus.regs[hiThreshRegister]; hi != 0x5DC0 {
+		t.Errorf("expected high threshold 0x5dc0, got %#04x", hi)
+	}
+	if c := bus.regs[configRegister]; c != 0x409E {
+		t.Errorf("expected config 0x409e, got %#04x", c)
+	}
+
+	if err := ch.SetComparator(ComparatorConfig{Low: 10, High: 5}); err == nil {
+		t.Error("inverted thresholds should fail")
+	}
+	if err := ch.SetComparator(ComparatorConfig{High: 4096}); err == nil {
+		t.Error("threshold beyond 12 bits should fail on ADS1015")
+	}
+	if err := ch.SetComparator(ComparatorConfig{Queue: 3}); err == nil {
+		t.Error("invalid queue should fail")
+	}
+}
@@ -32,10 +32,12 @@ func (d *driver) AnalogInputPins() []hal.AnalogInputPin {
 }
 
 func (d *driver) AnalogInputPin(n int) (hal.AnalogInputPin, error) {
-	if n < 0 || n > 3 {
-		return nil, fmt.Errorf("ADS1X15 does not have channel %d", n)
+	for _, ch := range d.channels {
+		if ch.Number() == n {
+			return ch, nil
+		}
 	}
-	return d.channels[n], nil
+	return nil, fmt.Errorf("ADS1X15 does not have channel %d", n)
 }
 
 func (d *driver) Close() error {
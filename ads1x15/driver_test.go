@@ -2,6 +2,7 @@ package ads1x15
 
 import (
 	"testing"
+	"time"
 
 	"github.com/reef-pi/hal"
 )
@@ -50,7 +51,7 @@ func TestAds1015Driver(t *testing.T) {
 	}
 
 	parameters := f.GetParameters()
-	if len(parameters) != 5 {
+	if len(parameters) != 14 {
 		t.Error("Incorrect number of parameters received")
 	}
 
@@ -141,7 +142,7 @@ func TestAds1115Driver(t *testing.T) {
 	}
 
 	parameters := f.GetParameters()
-	if len(parameters) != 5 {
+	if len(parameters) != 14 {
 		t.Error("Incorrect number of parameters received")
 	}
 
@@ -215,3 +216,118 @@ func TestAds1115Driver(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestAds1115Differential(t *testing.T) {
+	bus := mocki2cBus()
+	p := map[string]interface{}{
+		"Address":          72,
+		"Gain 1":           "2/3",
+		"Gain 2":           "2/3",
+		"Gain 3":           "2/3",
+		"Gain 4":           "2/3",
+		"Differential 0-1": true,
+		"Gain 0-1":         "16",
+		"Differential 2-3": false,
+	}
+	driver, err := Ads1115Factory().NewDriver(p, bus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := driver.(hal.AnalogInputDriver)
+	if len(d.AnalogInputPins()) != 5 {
+		t.Error("Expected 4 single ended and 1 differential channel, found:", len(d.AnalogInputPins()))
+	}
+	if _, err := d.AnalogInputPin(7); err == nil {
+		t.Error("Differential channel 2-3 should not be enabled")
+	}
+	ch, err := d.AnalogInputPin(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ch.Name() != "0-1" {
+		t.Error("Unexpected channel name:", ch.Name())
+	}
+	// config: single shot, AIN0-AIN1, gain 16, reading -2
+	bus.Bytes = []byte{0x8B, 0x83, 0xFF, 0xFE}
+	v, err := ch.Value()
+	if err != nil {
+		t.Error(err)
+	}
+	if v != -2 {
+		t.Error("Unexpected value:", v)
+	}
+	p["Differential 0-3"] = "yes"
+	if _, err := Ads1115Factory().NewDriver(p, bus); err == nil {
+		t.Error("Expected non boolean differential parameter to fail validation")
+	}
+}
+
+func TestAds1115Oversampling(t *testing.T) {
+	bus := mocki2cBus()
+	p := map[string]interface{}{
+		"Address":          72,
+		"Gain 1":           "2/3",
+		"Gain 2":           "2/3",
+		"Gain 3":           "2/3",
+		"Gain 4":           "2/3",
+		"Channel Sampling": "1=860 5 median",
+	}
+	driver, err := Ads1115Factory().NewDriver(p, bus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch, err := driver.(hal.AnalogInputDriver).AnalogInputPin(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := ch.(*channel).delay; d < time.Second/860 || d > 2*time.Millisecond {
+		t.Error("Unexpected conversion delay at 860 SPS:", d)
+	}
+	// config: single shot, AIN0, gain 2/3, 860 SPS
+	for _, r := range []byte{10, 12, 11, 100, 9} {
+		bus.Bytes = append(bus.Bytes, 0xC1, 0xE3, 0x00, r)
+	}
+	v, err := ch.Value()
+	if err != nil {
+		t.Error(err)
+	}
+	if v != 11 {
+		t.Error("Expected median 11, found:", v)
+	}
+
+	for _, tc := range []struct {
+		filter   string
+		expected float64
+	}{
+		{filterMean, 28.4},
+		{filterMedian, 11},
+		{filterTrimmed, 11},
+	} {
+		if v := reduce([]float64{10, 12, 11, 100, 9}, tc.filter); v != tc.expected {
+			t.Error("Expected", tc.filter, tc.expected, "found:", v)
+		}
+	}
+
+	p["Channel Sampling"] = "1=1600"
+	if _, err := Ads1115Factory().NewDriver(p, bus); err == nil {
+		t.Error("1600 SPS should not be accepted by ADS1115")
+	}
+	if _, err := Ads1015Factory().NewDriver(p, bus); err != nil {
+		t.Error(err)
+	}
+	for _, invalid := range []interface{}{"5=860", "1=860 0", "1=860 5 mode", "1", 860} {
+		p["Channel Sampling"] = invalid
+		if _, err := Ads1015Factory().NewDriver(p, bus); err == nil {
+			t.Error("Expected channel sampling", invalid, "to fail validation")
+		}
+	}
+	p["Channel Sampling"] = "0-1=250 4; 2=490"
+	driver, err = Ads1015Factory().NewDriver(p, bus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch, _ = driver.(hal.AnalogInputDriver).AnalogInputPin(1)
+	if c := ch.(*channel); c.dataRate != configDataRate490 || c.samples != 1 {
+		t.Error("Unexpected sampling of channel 2:", c.dataRate, c.samples)
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
-	"time"
 
 	"github.com/reef-pi/hal"
 	"github.com/reef-pi/rpi/i2c"
@@ -14,6 +13,30 @@ const addressParam = "Address"
 
 var channelAddresses = [4]uint16{configMuxSingle0, configMuxSingle1, configMuxSingle2, configMuxSingle3}
 var channelGains = [4]string{"Gain 1", "Gain 2", "Gain 3", "Gain 4"}
+
+// differentialPair measures the voltage between two inputs. Differential
+// channels are numbered after the four single ended ones.
+type differentialPair struct {
+	name       string
+	pinAddress uint16
+	number     int
+}
+
+var differentialPairs = [4]differentialPair{
+	{name: "0-1", pinAddress: configMuxDiff01, number: 4},
+	{name: "0-3", pinAddress: configMuxDiff03, number: 5},
+	{name: "1-3", pinAddress: configMuxDiff13, number: 6},
+	{name: "2-3", pinAddress: configMuxDiff23, number: 7},
+}
+
+func (p differentialPair) enableParam() string {
+	return "Differential " + p.name
+}
+
+func (p differentialPair) gainParam() string {
+	return "Gain " + p.name
+}
+
 var gainOptions = map[string]uint16{
 	"2/3": configGainTwoThirds,
 	"1":   configGainOne,
@@ -21,11 +44,24 @@ var gainOptions = map[string]uint16{
 	"4":   configGainFour,
 	"8":   configGainEight,
 	"16":  configGainSixteen,
+	// auto ranging starts at the coarsest gain
+	gainAuto: configGainTwoThirds,
 }
 
 type ads1X15Factory struct {
 	meta       hal.Metadata
 	parameters []hal.ConfigParameter
+	dataRates  [8]int
+}
+
+// channelKeys identifies the single ended channels in parameter names,
+// followed by the differential pairs
+func channelKeys() []string {
+	keys := []string{"1", "2", "3", "4"}
+	for _, pair := range differentialPairs {
+		keys = append(keys, pair.name)
+	}
+	return keys
 }
 
 func (f *ads1X15Factory) appendParameters() {
@@ -47,6 +83,30 @@ func (f *ads1X15Factory) appendParameters() {
 		}
 		f.parameters = append(f.parameters, gainParam)
 	}
+
+	for _, pair := range differentialPairs {
+		f.parameters = append(f.parameters,
+			hal.ConfigParameter{
+				Name:    pair.enableParam(),
+				Type:    hal.Boolean,
+				Order:   len(f.parameters),
+				Default: false,
+			},
+			hal.ConfigParameter{
+				Name:    pair.gainParam(),
+				Type:    hal.String,
+				Order:   len(f.parameters) + 1,
+				Default: "2/3",
+			},
+		)
+	}
+
+	f.parameters = append(f.parameters, hal.ConfigParameter{
+		Name:    samplingParam,
+		Type:    hal.String,
+		Order:   len(f.parameters),
+		Default: "",
+	})
 }
 
 func (f *ads1X15Factory) Metadata() hal.Metadata {
@@ -89,6 +149,26 @@ func (f *ads1X15Factory) ValidateParameters(parameters map[string]interface{}) (
 			failures[channelGain] = append(failures[channelGain], failure)
 		}
 	}
+
+	for _, pair := range differentialPairs {
+		if v, ok = parameters[pair.enableParam()]; ok {
+			if _, ok := v.(bool); !ok {
+				failure := fmt.Sprint(pair.enableParam(), " is not a boolean. ", v, " was received.")
+				failures[pair.enableParam()] = append(failures[pair.enableParam()], failure)
+			}
+		}
+		if v, ok = parameters[pair.gainParam()]; ok {
+			if _, err := parseGain(v); err != nil {
+				failures[pair.gainParam()] = append(failures[pair.gainParam()], fmt.Sprint(pair.gainParam(), err.Error()))
+			}
+		}
+	}
+
+	if v, ok = parameters[samplingParam]; ok {
+		if _, err := parseSampling(v, f.dataRates); err != nil {
+			failures[samplingParam] = append(failures[samplingParam], fmt.Sprint(samplingParam, err.Error()))
+		}
+	}
 	return len(failures) == 0, failures
 }
 
@@ -110,14 +190,31 @@ func parseGain(v interface{}) (string, error) {
 	}
 
 	if _, ok = gainOptions[val.(string)]; !ok {
-		failure := fmt.Sprint(" is not a valid value of 2/3, 1, 2, 4, 8, or 16. ", v, " was received.")
+		failure := fmt.Sprint(" is not a valid value of 2/3, 1, 2, 4, 8, 16, or auto. ", v, " was received.")
 		return "", fmt.Errorf(failure)
 	}
 
 	return val.(string), nil
 }
 
-func (f *ads1X15Factory) newDriver(parameters map[string]interface{}, hardwareResources interface{}, shift int, delay time.Duration) (hal.Driver, error) {
+// channelConfig returns the sampling configuration of the channel identified
+// by key. Parameters must have been validated.
+func (f *ads1X15Factory) channelConfig(parameters map[string]interface{}, key string, shift int) channelConfig {
+	c := channelConfig{
+		dataRate: defaultDataRate,
+		samples:  1,
+		filter:   filterMean,
+		shift:    shift,
+	}
+	settings, _ := parseSampling(parameters[samplingParam], f.dataRates)
+	if s, ok := settings[key]; ok {
+		c.dataRate, c.samples, c.filter = s.dataRate, s.samples, s.filter
+	}
+	c.delay = conversionDelay(f.dataRates[c.dataRate>>5])
+	return c
+}
+
+func (f *ads1X15Factory) newDriver(parameters map[string]interface{}, hardwareResources interface{}, shift int) (hal.Driver, error) {
 	if valid, failures := f.ValidateParameters(parameters); !valid {
 		return nil, errors.New(hal.ToErrorString(failures))
 	}
@@ -136,10 +233,16 @@ func (f *ads1X15Factory) newDriver(parameters map[string]interface{}, hardwareRe
 		channels: []hal.AnalogInputPin{},
 	}
 
+	keys := channelKeys()
+	ic := new(chip)
+
 	// Create the 4 channels the hardware has
 	for i, channelAddress := range channelAddresses {
 		gain, _ := parseGain(parameters[channelGains[i]])
-		ch, err := newChannel(bus, address, i, channelAddress, gainOptions[gain], shift, delay)
+		c := f.channelConfig(parameters, keys[i], shift)
+		c.number, c.name, c.pinAddress, c.gain = i, fmt.Sprint(i), channelAddress, gainOptions[gain]
+		c.autoGain, c.chip = gain == gainAuto, ic
+		ch, err := newChannel(bus, address, c)
 		if err != nil {
 			return nil, err
 		}
@@ -147,5 +250,23 @@ func (f *ads1X15Factory) newDriver(parameters map[string]interface{}, hardwareRe
 		driver.channels = append(driver.channels, ch)
 	}
 
+	for _, pair := range differentialPairs {
+		if enabled, _ := parameters[pair.enableParam()].(bool); !enabled {
+			continue
+		}
+		gain := "2/3"
+		if v, ok := parameters[pair.gainParam()]; ok {
+			gain, _ = parseGain(v)
+		}
+		c := f.channelConfig(parameters, pair.name, shift)
+		c.number, c.name, c.pinAddress, c.gain = pair.number, pair.name, pair.pinAddress, gainOptions[gain]
+		c.autoGain, c.chip = gain == gainAuto, ic
+		ch, err := newChannel(bus, address, c)
+		if err != nil {
+			return nil, err
+		}
+		driver.channels = append(driver.channels, ch)
+	}
+
 	return &driver, nil
 }
@@ -0,0 +1,175 @@
+package ads1x15
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/reef-pi/hal"
+)
+
+// Data rates in samples per second, indexed by the DR bits of the config
+// register (bits 7:5)
+var (
+	ads1015DataRates = [8]int{128, 250, 490, 920, 1600, 2400, 3300, 3300}
+	ads1115DataRates = [8]int{8, 16, 32, 64, 128, 250, 475, 860}
+)
+
+// defaultDataRate is the DR setting used when none is configured, 1600 SPS on
+// ADS1015 and 128 SPS on ADS1115
+const defaultDataRate = configDataRate1600
+
+const (
+	filterMean    = "mean"
+	filterMedian  = "median"
+	filterTrimmed = "trimmed"
+
+	maxSamples = 64
+)
+
+const samplingParam = "Channel Sampling"
+
+// sampling is the data rate and oversampling of a channel
+type sampling struct {
+	dataRate uint16
+	samples  int
+	filter   string
+}
+
+// parseSampling parses channel specific sampling, as semicolon separated
+// channel=settings pairs (e.g. "1=860 5 median; 0-1=128"). Channels are named
+// as in the gain parameters. Settings are the data rate in samples per
+// second, optionally followed by the number of samples and the filter
+// combining them. Channels left out sample once at the default data rate.
+func parseSampling(v interface{}, rates [8]int) (map[string]sampling, error) {
+	if v == nil {
+		return nil, nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf(" is not a string. %v was received.", v)
+	}
+	settings := make(map[string]sampling)
+	for _, pair := range strings.Split(s, ";") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		fields := []string{}
+		if len(kv) == 2 {
+			fields = strings.Fields(kv[1])
+		}
+		if len(fields) == 0 || len(fields) > 3 {
+			return nil, fmt.Errorf(" has an invalid channel sampling %q.", pair)
+		}
+		key := strings.TrimSpace(kv[0])
+		if !validKey(key) {
+			return nil, fmt.Errorf(" has an unknown channel %q.", key)
+		}
+		c := sampling{samples: 1, filter: filterMean}
+		var err error
+		if c.dataRate, err = parseDataRate(fields[0], rates); err != nil {
+			return nil, fmt.Errorf(" data rate%s", err.Error())
+		}
+		if len(fields) > 1 {
+			if c.samples, err = parseSamples(fields[1]); err != nil {
+				return nil, fmt.Errorf(" samples%s", err.Error())
+			}
+		}
+		if len(fields) > 2 {
+			if c.filter, err = parseFilter(fields[2]); err != nil {
+				return nil, fmt.Errorf(" filter%s", err.Error())
+			}
+		}
+		settings[key] = c
+	}
+	return settings, nil
+}
+
+func validKey(key string) bool {
+	for _, k := range channelKeys() {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// conversionDelay returns how long to wait for a single shot conversion at
+// the given data rate, with 10% margin for the internal oscillator tolerance
+func conversionDelay(sps int) time.Duration {
+	return time.Second*11/time.Duration(sps*10) + 100*time.Microsecond
+}
+
+// parseDataRate returns the DR bits for a data rate in samples per second
+func parseDataRate(v interface{}, rates [8]int) (uint16, error) {
+	sps, ok := hal.ConvertToInt(v)
+	if !ok {
+		return 0, fmt.Errorf(" is not an integer. %v was received.", v)
+	}
+	for i, r := range rates {
+		if r == sps {
+			return uint16(i) << 5, nil
+		}
+	}
+	return 0, fmt.Errorf(" is not a supported data rate %v. %v was received.", rates, v)
+}
+
+func parseSamples(v interface{}) (int, error) {
+	n, ok := hal.ConvertToInt(v)
+	if !ok {
+		return 0, fmt.Errorf(" is not an integer. %v was received.", v)
+	}
+	if n < 1 || n > maxSamples {
+		return 0, fmt.Errorf(" is out of range (1 - %d). %v was received.", maxSamples, v)
+	}
+	return n, nil
+}
+
+func parseFilter(v interface{}) (string, error) {
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf(" is not a string. %v was received.", v)
+	}
+	switch s {
+	case filterMean, filterMedian, filterTrimmed:
+		return s, nil
+	default:
+		return "", fmt.Errorf(" is not a valid value of mean, median or trimmed. %v was received.", v)
+	}
+}
+
+// reduce combines oversampled readings. The trimmed mean discards the lowest
+// and highest quarter of the readings before averaging.
+func reduce(vs []float64, filter string) float64 {
+	switch filter {
+	case filterMedian:
+		s := sorted(vs)
+		n := len(s)
+		if n%2 == 1 {
+			return s[n/2]
+		}
+		return (s[n/2-1] + s[n/2]) / 2
+	case filterTrimmed:
+		s := sorted(vs)
+		k := len(s) / 4
+		return mean(s[k : len(s)-k])
+	default:
+		return mean(vs)
+	}
+}
+
+func sorted(vs []float64) []float64 {
+	s := append([]float64(nil), vs...)
+	sort.Float64s(s)
+	return s
+}
+
+func mean(vs []float64) float64 {
+	var sum float64
+	for _, v := range vs {
+		sum += v
+	}
+	return sum / float64(len(vs))
+}
@@ -0,0 +1,72 @@
+package esp32
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/reef-pi/hal"
+)
+
+// PinInfo describes a single pin as reported by the firmware's
+// GET /capabilities endpoint.
+type PinInfo struct {
+	Number       int      `json:"number"`
+	Name         string   `json:"name"`
+	Capabilities []string `json:"capabilities"`
+}
+
+// Capabilities is the discovery document served by the firmware, e.g.
+//
+//	{"pins":[{"number":2,"name":"heater","capabilities":["digital-output","pwm"]}]}
+//
+// Capability names are the same as hal.Capability.String().
+type Capabilities struct {
+	Pins []PinInfo `json:"pins"`
+}
+
+func string2cap(s string) (hal.Capability, error) {
+	for _, c := range []hal.Capability{hal.DigitalOutput, hal.DigitalInput, hal.PWM, hal.AnalogInput} {
+		if c.String() == s {
+			return c, nil
+		}
+	}
+	return hal.None, fmt.Errorf("unknown capability:%s", s)
+}
+
+// PinsFor returns the numbers of all pins that support capability c.
+// Capabilities unknown to this driver, e.g. added by newer firmware, are
+// skipped.
+func (cs *Capabilities) PinsFor(c hal.Capability) []int {
+	var pins []int
+	for _, p := range cs.Pins {
+		for _, s := range p.Capabilities {
+			if pc, err := string2cap(s); err == nil && pc == c {
+				pins = append(pins, p.Number)
+				break
+			}
+		}
+	}
+	return pins
+}
+
+func (d *driver) discover() (*Capabilities, error) {
+	uri := fmt.Sprintf("%s://%s/capabilities", d.scheme, d.address)
+	req, err := newRequest(http.MethodGet, uri, d.token, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := d.client(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query capabilities. Error:%w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("failed to query capabilities. HTTP Code:%d", resp.StatusCode)
+	}
+	var cs Capabilities
+	if err := json.NewDecoder(resp.Body).Decode(&cs); err != nil {
+		return nil, fmt.Errorf("failed to decode capabilities. Error:%w", err)
+	}
+	return &cs, nil
+}
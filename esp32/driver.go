@@ -1,27 +1,42 @@
 package esp32
 
 import (
+	"context"
 	"fmt"
 	"github.com/reef-pi/hal"
 	"net/http"
+	"sync"
 	"time"
 )
 
 const (
 	_driverName = "esp32"
 	_timeout    = 3 * time.Second
+	_stateTTL   = 10 * time.Second
 )
 
 type HTTPClient func(*http.Request) (*http.Response, error)
 
 type driver struct {
-	meta    hal.Metadata
-	address string
-	pins    map[hal.Capability][]int
-	client  HTTPClient
+	meta     hal.Metadata
+	scheme   string
+	address  string
+	token    string
+	pins     map[hal.Capability][]int
+	names    map[int]string
+	client   HTTPClient
+	mu       sync.Mutex
+	cache    map[hal.Capability]map[int]*pin
+	handlers []InputChangeHandler
+	cancel   context.CancelFunc
+	done     chan struct{}
 }
 
 func (d *driver) Close() error {
+	if d.cancel != nil {
+		d.cancel()
+		<-d.done
+	}
 	return nil
 }
 
@@ -53,13 +68,34 @@ func (d *driver) PWMChannels() []hal.PWMChannel {
 	return channels
 }
 
+// halPin returns the pin object for capability c and number p. Pin objects
+// are created once and reused, so cached state survives between calls.
 func (d *driver) halPin(c hal.Capability, p int) *pin {
-	return &pin{
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.cache == nil {
+		d.cache = make(map[hal.Capability]map[int]*pin)
+	}
+	if d.cache[c] == nil {
+		d.cache[c] = make(map[int]*pin)
+	}
+	if hp, ok := d.cache[c][p]; ok {
+		return hp
+	}
+	hp := &pin{
+		scheme:  d.scheme,
 		address: d.address,
+		token:   d.token,
 		number:  p,
+		name:    d.names[p],
 		cap:     c,
 		client:  d.client,
 	}
+	if c == hal.AnalogInput {
+		hp.calibrator, _ = hal.CalibratorFactory([]hal.Measurement{})
+	}
+	d.cache[c][p] = hp
+	return hp
 }
 
 func (d *driver) PWMChannel(i int) (hal.PWMChannel, error) {
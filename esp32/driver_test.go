@@ -87,3 +87,182 @@ func TestESP32Driver(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func discoveryClient(r *http.Request) (*http.Response, error) {
+	resp := new(http.Response)
+	resp.StatusCode = 200
+	switch r.URL.Path {
+	case "/capabilities":
+		resp.Body = io.NopCloser(bytes.NewBufferString(`{"pins":[
+			{"number":2,"name":"heater","capabilities":["digital-output","pwm"]},
+			{"number":5,"name":"float switch","capabilities":["digital-input"]},
+			{"number":34,"name":"ph","capabilities":["analog-input"]},
+			{"number":21,"name":"bus","capabilities":["i2c"]}
+		]}`))
+	default:
+		resp.Body = io.NopCloser(bytes.NewBufferString("1"))
+	}
+	return resp, nil
+}
+
+func TestESP32Discovery(t *testing.T) {
+	f := newFactory(discoveryClient)
+	d, err := f.NewDriver(map[string]interface{}{"Address": "192.168.86.2"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pd := d.(hal.PWMDriver)
+	chs := pd.PWMChannels()
+	if len(chs) != 1 {
+		t.Fatal("expected 1 pwm channel, found:", len(chs))
+	}
+	if chs[0].Number() != 2 {
+		t.Error("expected pwm channel 2, found:", chs[0].Number())
+	}
+	if chs[0].Name() != "heater" {
+		t.Error("expected discovered pin name 'heater', found:", chs[0].Name())
+	}
+	if len(pd.DigitalOutputPins()) != 1 {
+		t.Error("expected 1 digital output pin, found:", len(pd.DigitalOutputPins()))
+	}
+	di := d.(hal.DigitalInputDriver)
+	if _, err := di.DigitalInputPin(5); err != nil {
+		t.Error(err)
+	}
+	ad := d.(hal.AnalogInputDriver)
+	if _, err := ad.AnalogInputPin(34); err != nil {
+		t.Error(err)
+	}
+
+	// explicit counts, including zero, take precedence over discovered pins
+	d, err = f.NewDriver(map[string]interface{}{"Address": "192.168.86.2", "Pwm": 3, "Analog-Input": 0}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(d.(hal.PWMDriver).PWMChannels()); n != 3 {
+		t.Error("expected 3 pwm channels, found:", n)
+	}
+	if n := len(d.(hal.DigitalInputDriver).DigitalInputPins()); n != 1 {
+		t.Error("expected 1 discovered digital input pin, found:", n)
+	}
+	if n := len(d.(hal.AnalogInputDriver).AnalogInputPins()); n != 0 {
+		t.Error("expected no analog input pins for a zero count, found:", n)
+	}
+}
+
+func TestESP32StateReadBack(t *testing.T) {
+	requests := 0
+	client := func(r *http.Request) (*http.Response, error) {
+		requests++
+		resp := new(http.Response)
+		resp.StatusCode = 200
+		switch r.URL.Path {
+		case "/outlets/1":
+			resp.Body = io.NopCloser(bytes.NewBufferString("true\n"))
+		case "/jacks/0":
+			resp.Body = io.NopCloser(bytes.NewBufferString("51"))
+		default:
+			resp.Body = io.NopCloser(bytes.NewBufferString(""))
+		}
+		return resp, nil
+	}
+	f := newFactory(client)
+	d, err := f.NewDriver(map[string]interface{}{
+		"Address":        "192.168.86.2",
+		"Digital-Output": 2,
+		"Digital-Input":  0,
+		"Pwm":            1,
+		"Analog-Input":   0,
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pd := d.(hal.PWMDriver)
+	o, err := pd.DigitalOutputPin(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !o.LastState() {
+		t.Error("expected outlet 1 state to be read back as on")
+	}
+	n := requests
+	if !o.LastState() {
+		t.Error("expected cached outlet 1 state to be on")
+	}
+	if requests != n {
+		t.Error("expected cached state to be used without a request")
+	}
+	if err := o.Write(false); err != nil {
+		t.Error(err)
+	}
+	if o.LastState() {
+		t.Error("expected state to follow last write")
+	}
+
+	ch, err := pd.PWMChannel(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sr, ok := ch.(StateReader)
+	if !ok {
+		t.Fatal("expected pwm channel to implement StateReader")
+	}
+	v, err := sr.Get()
+	if err != nil {
+		t.Error(err)
+	}
+	if v != 20 {
+		t.Error("expected duty cycle 20, found:", v)
+	}
+	if !ch.LastState() {
+		t.Error("expected pwm channel with non zero duty to be on")
+	}
+}
+
+func TestESP32AnalogCalibration(t *testing.T) {
+	f := newFactory(NopClient)
+	d, err := f.NewDriver(map[string]interface{}{
+		"Address":        "192.168.86.2",
+		"Digital-Output": 1,
+		"Digital-Input":  0,
+		"Pwm":            1,
+		"Analog-Input":   2,
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ad := d.(hal.AnalogInputDriver)
+	a, err := ad.AnalogInputPin(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := a.Measure()
+	if err != nil {
+		t.Error(err)
+	}
+	if v != 2 {
+		t.Error("expected uncalibrated measurement 2, found:", v)
+	}
+	if err := a.Calibrate([]hal.Measurement{{Expected: 7, Observed: 1}, {Expected: 10, Observed: 4}}); err != nil {
+		t.Error(err)
+	}
+	// calibration must survive looking the pin up again
+	a, err = ad.AnalogInputPin(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err = a.Measure()
+	if err != nil {
+		t.Error(err)
+	}
+	if v != 8 {
+		t.Error("expected calibrated measurement 8, found:", v)
+	}
+	if v, _ := ad.AnalogInputPins()[0].Measure(); v != 2 {
+		t.Error("calibration of pin 1 should not affect pin 0, found:", v)
+	}
+	pwm, _ := d.(hal.PWMDriver).PWMChannel(0)
+	if err := pwm.(*pin).Calibrate(nil); err == nil {
+		t.Error("expected calibrating a pwm channel to fail")
+	}
+}
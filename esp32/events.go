@@ -0,0 +1,164 @@
+package esp32
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/reef-pi/hal"
+)
+
+const (
+	_minBackoff = time.Second
+	_maxBackoff = 30 * time.Second
+	_inletEvent = "inlet"
+)
+
+// InputChangeHandler is invoked with the pin number and new state whenever
+// the firmware pushes a digital input change.
+type InputChangeHandler func(number int, state bool)
+
+// InputNotifier is implemented by ESP32 drivers to notify digital input
+// changes pushed by the firmware when the Events parameter is enabled.
+type InputNotifier interface {
+	OnInputChange(InputChangeHandler)
+}
+
+// InletEvent is the payload of an "inlet" server-sent event. The firmware
+// serves the stream from GET /events, sending the current state of every
+// input right after a client connects and then one event per change:
+//
+//	event: inlet
+//	data: {"number":5,"state":true}
+type InletEvent struct {
+	Number int  `json:"number"`
+	State  bool `json:"state"`
+}
+
+// OnInputChange registers h to be called on every pushed digital input change.
+func (d *driver) OnInputChange(h InputChangeHandler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.handlers = append(d.handlers, h)
+}
+
+func (d *driver) startEvents(client HTTPClient) {
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+	d.done = make(chan struct{})
+	go d.events(ctx, client)
+}
+
+// events keeps the event stream open, reconnecting with exponential backoff.
+// While disconnected, digital inputs fall back to polling.
+func (d *driver) events(ctx context.Context, client HTTPClient) {
+	defer close(d.done)
+	backoff := _minBackoff
+	for {
+		start := time.Now()
+		err := d.stream(ctx, client)
+		d.setLive(false)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(start) > _maxBackoff {
+			backoff = _minBackoff
+		}
+		log.Println("esp32: event stream closed, falling back to polling. Error:", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > _maxBackoff {
+			backoff = _maxBackoff
+		}
+	}
+}
+
+func (d *driver) stream(ctx context.Context, client HTTPClient) error {
+	req, err := newRequest(http.MethodGet, fmt.Sprintf("%s://%s/events", d.scheme, d.address), d.token, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := client(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("HTTP Code:%d", resp.StatusCode)
+	}
+	var event, data string
+	s := bufio.NewScanner(resp.Body)
+	for s.Scan() {
+		line := s.Text()
+		switch {
+		case line == "":
+			if event == _inletEvent {
+				if err := d.dispatch(data); err != nil {
+					log.Println("esp32: ignoring malformed event. Error:", err)
+				}
+			}
+			event, data = "", ""
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data += strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		}
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("stream ended")
+}
+
+func (d *driver) dispatch(data string) error {
+	var e InletEvent
+	if err := json.Unmarshal([]byte(data), &e); err != nil {
+		return err
+	}
+	known := false
+	for _, n := range d.pins[hal.DigitalInput] {
+		if n == e.Number {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return fmt.Errorf("unknown digital input pin %d", e.Number)
+	}
+	p := d.halPin(hal.DigitalInput, e.Number)
+	p.mu.Lock()
+	changed := !p.live || p.state != e.State
+	p.live = true
+	p.state = e.State
+	p.updated = time.Now()
+	p.mu.Unlock()
+	if !changed {
+		return nil
+	}
+	d.mu.Lock()
+	handlers := append([]InputChangeHandler(nil), d.handlers...)
+	d.mu.Unlock()
+	for _, h := range handlers {
+		h(e.Number, e.State)
+	}
+	return nil
+}
+
+func (d *driver) setLive(live bool) {
+	for _, n := range d.pins[hal.DigitalInput] {
+		p := d.halPin(hal.DigitalInput, n)
+		p.mu.Lock()
+		p.live = live
+		p.mu.Unlock()
+	}
+}
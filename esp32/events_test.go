@@ -0,0 +1,97 @@
+package esp32
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/reef-pi/hal"
+)
+
+func TestESP32InletEvents(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/events":
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "event: inlet\ndata: {\"number\":1,\"state\":true}\n\n")
+			fmt.Fprint(w, ": keepalive\n\n")
+			w.(http.Flusher).Flush()
+			<-release
+			fmt.Fprint(w, "event: inlet\ndata: {\"number\":1,\"state\":false}\n\n")
+		case "/inlets/1":
+			fmt.Fprint(w, "true")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := &http.Client{}
+	f := newFactory(c.Do)
+	d, err := f.NewDriver(map[string]interface{}{
+		"Address":        strings.TrimPrefix(srv.URL, "http://"),
+		"Digital-Output": 0,
+		"Digital-Input":  2,
+		"Pwm":            0,
+		"Analog-Input":   0,
+		"Events":         true,
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	changes := make(chan bool, 4)
+	notifier, ok := d.(InputNotifier)
+	if !ok {
+		t.Fatal("expected driver to implement InputNotifier")
+	}
+	notifier.OnInputChange(func(n int, s bool) {
+		if n == 1 {
+			changes <- s
+		}
+	})
+	i, _ := d.(hal.DigitalInputDriver).DigitalInputPin(1)
+	live := func() bool {
+		p := i.(*pin)
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return p.live
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for !live() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if v, err := i.Read(); err != nil || !v {
+		t.Error("expected pushed state true, found:", v, err)
+	}
+	close(release)
+	// the initial snapshot may or may not be seen by the handler
+	for s := true; s; {
+		select {
+		case s = <-changes:
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for inlet event")
+		}
+	}
+
+	// once the stream ends, reads fall back to polling the firmware
+	deadline = time.Now().Add(2 * time.Second)
+	for {
+		v, err := i.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected polled state after stream closed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
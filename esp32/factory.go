@@ -4,7 +4,6 @@ import (
 	"errors"
 	"fmt"
 	"github.com/reef-pi/hal"
-	"net/http"
 	"strings"
 	"sync"
 )
@@ -18,69 +17,107 @@ type factory struct {
 var esp32DriverFactory *factory
 var once sync.Once
 
-const Address = "Address"
+const (
+	Address     = "Address"
+	Token       = "Token"
+	HTTPS       = "HTTPS"
+	Fingerprint = "Fingerprint"
+	Events      = "Events"
+)
 
 func cap2string(c hal.Capability) string {
 	return strings.Title(c.String())
 }
 
+// Factory returns a singleton ESP32 driver factory. Drivers created by it use
+// their own HTTP client, pinned to the certificate fingerprint when one is
+// configured.
 func Factory() hal.DriverFactory {
-	client := http.Client{Timeout: _timeout}
-	return FactoryWithClient(client.Do)
+	return FactoryWithClient(nil)
 }
-func FactoryWithClient(c HTTPClient) hal.DriverFactory {
 
+// FactoryWithClient returns a singleton ESP32 driver factory that sends all
+// requests through c. Certificate pinning can not be enforced on c, so
+// drivers configured with a fingerprint fail to be created.
+func FactoryWithClient(c HTTPClient) hal.DriverFactory {
 	once.Do(func() {
-		esp32DriverFactory = &factory{
-			client: c,
-			meta: hal.Metadata{
-				Name:        "reef-pi ESP32 driver",
-				Description: "Simple HTTP based full featured HAL driver for reef-pi",
-				Capabilities: []hal.Capability{
-					hal.PWM,
-					hal.DigitalOutput,
-					hal.DigitalInput,
-					hal.AnalogInput,
-				},
-			},
-			parameters: []hal.ConfigParameter{
-				{
-					Name:    Address,
-					Type:    hal.String,
-					Order:   0,
-					Default: "192.1.168.4",
-				},
-				{
-					Name:    cap2string(hal.DigitalOutput),
-					Type:    hal.Integer,
-					Order:   1,
-					Default: 6,
-				},
-				{
-					Name:    cap2string(hal.DigitalInput),
-					Type:    hal.Integer,
-					Order:   2,
-					Default: 4,
-				},
-				{
-					Name:    cap2string(hal.PWM),
-					Type:    hal.Integer,
-					Order:   3,
-					Default: 4,
-				},
-				{
-					Name:    cap2string(hal.AnalogInput),
-					Type:    hal.Integer,
-					Order:   4,
-					Default: 2,
-				},
-			},
-		}
+		esp32DriverFactory = newFactory(c)
 	})
-
 	return esp32DriverFactory
 }
 
+func newFactory(c HTTPClient) *factory {
+	return &factory{
+		client: c,
+		meta: hal.Metadata{
+			Name:        "reef-pi ESP32 driver",
+			Description: "Simple HTTP based full featured HAL driver for reef-pi",
+			Capabilities: []hal.Capability{
+				hal.PWM,
+				hal.DigitalOutput,
+				hal.DigitalInput,
+				hal.AnalogInput,
+			},
+		},
+		parameters: []hal.ConfigParameter{
+			{
+				Name:    Address,
+				Type:    hal.String,
+				Order:   0,
+				Default: "192.1.168.4",
+			},
+			{
+				Name:    cap2string(hal.DigitalOutput),
+				Type:    hal.Integer,
+				Order:   1,
+				Default: 6,
+			},
+			{
+				Name:    cap2string(hal.DigitalInput),
+				Type:    hal.Integer,
+				Order:   2,
+				Default: 4,
+			},
+			{
+				Name:    cap2string(hal.PWM),
+				Type:    hal.Integer,
+				Order:   3,
+				Default: 4,
+			},
+			{
+				Name:    cap2string(hal.AnalogInput),
+				Type:    hal.Integer,
+				Order:   4,
+				Default: 2,
+			},
+			{
+				Name:    Token,
+				Type:    hal.String,
+				Order:   5,
+				Default: "",
+			},
+			{
+				Name:    HTTPS,
+				Type:    hal.Boolean,
+				Order:   6,
+				Default: false,
+			},
+			{
+				Name:    Fingerprint,
+				Type:    hal.String,
+				Order:   7,
+				Default: "",
+			},
+			{
+				Name:    Events,
+				Type:    hal.Boolean,
+				Order:   8,
+				Default: false,
+			},
+		},
+	}
+}
+
 func (f *factory) GetParameters() []hal.ConfigParameter {
 	return f.parameters
 }
@@ -116,6 +153,25 @@ func (f *factory) ValidateParameters(parameters map[string]interface{}) (bool, m
 			}
 		}
 	}
+	for _, k := range []string{Token, Fingerprint} {
+		if v, ok := parameters[k]; ok {
+			if _, ok := v.(string); !ok {
+				failures[k] = append(failures[k], fmt.Sprint(k, " is not a string. ", v, " was received."))
+			}
+		}
+	}
+	for _, k := range []string{HTTPS, Events} {
+		if v, ok := parameters[k]; ok {
+			if _, ok := v.(bool); !ok {
+				failures[k] = append(failures[k], fmt.Sprint(k, " is not a boolean. ", v, " was received."))
+			}
+		}
+	}
+	if v, ok := parameters[Fingerprint].(string); ok && v != "" {
+		if _, err := parseFingerprint(v); err != nil {
+			failures[Fingerprint] = append(failures[Fingerprint], err.Error())
+		}
+	}
 	return len(failures) == 0, failures
 }
 
@@ -127,22 +183,67 @@ func (f *factory) NewDriver(parameters map[string]interface{}, hardwareResources
 	if valid, failures := f.ValidateParameters(parameters); !valid {
 		return nil, errors.New(hal.ToErrorString(failures))
 	}
-	pins := make(map[hal.Capability][]int)
+	d := &driver{
+		meta:    f.meta,
+		scheme:  "http",
+		address: parameters[Address].(string),
+		pins:    make(map[hal.Capability][]int),
+		names:   make(map[int]string),
+		client:  f.client,
+	}
+	if v, ok := parameters[Token]; ok {
+		d.token = v.(string)
+	}
+	var fingerprint string
+	if v, ok := parameters[Fingerprint]; ok {
+		fingerprint = v.(string)
+	}
+	if v, ok := parameters[HTTPS]; (ok && v.(bool)) || fingerprint != "" {
+		d.scheme = "https"
+	}
+	if d.client != nil && fingerprint != "" {
+		return nil, errors.New("certificate fingerprint can not be enforced on an injected HTTP client")
+	}
+	streamClient := d.client
+	if d.client == nil {
+		c, err := newHTTPClient(fingerprint, _timeout)
+		if err != nil {
+			return nil, err
+		}
+		d.client = c
+		if streamClient, err = newHTTPClient(fingerprint, 0); err != nil {
+			return nil, err
+		}
+	}
+	// Pins are discovered from the firmware for counts that are not given.
+	// An explicit count, including zero, is used as is.
+	var discovered *Capabilities
 	for _, c := range []hal.Capability{hal.DigitalOutput, hal.DigitalInput, hal.PWM, hal.AnalogInput} {
-		if v, ok := parameters[cap2string(c)]; ok {
-			val, ok := hal.ConvertToInt(v)
-			if !ok {
-				return nil, fmt.Errorf("failed to type cast '%s' parameter value '%v' as integer", c, v)
-			}
-			for i := 0; i < val; i++ {
-				pins[c] = append(pins[c], i)
+		v, ok := parameters[cap2string(c)]
+		if !ok {
+			if discovered == nil {
+				cs, err := d.discover()
+				if err != nil {
+					return nil, err
+				}
+				discovered = cs
+				for _, p := range cs.Pins {
+					d.names[p.Number] = p.Name
+				}
 			}
+			d.pins[c] = discovered.PinsFor(c)
+			continue
+		}
+		val, ok := hal.ConvertToInt(v)
+		if !ok {
+			return nil, fmt.Errorf("failed to type cast '%s' parameter value '%v' as integer", c, v)
+		}
+		for i := 0; i < val; i++ {
+			d.pins[c] = append(d.pins[c], i)
 		}
 	}
-	return &driver{
-		meta:    f.meta,
-		address: parameters[Address].(string),
-		pins:    pins,
-		client:  f.client,
-	}, nil
+	if v, ok := parameters[Events]; ok && v.(bool) && len(d.pins[hal.DigitalInput]) > 0 {
+		d.startEvents(streamClient)
+	}
+	return d, nil
 }
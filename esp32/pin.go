@@ -9,19 +9,29 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
-var _notImplemented = errors.New("not implemented")
-
 const _true = "true"
 
 var ErrIncompatibleCapability = errors.New("incompatible capability")
 
 type pin struct {
+	scheme  string
 	address string
+	token   string
 	number  int
+	name    string
 	cap     hal.Capability
 	client  HTTPClient
+
+	mu         sync.Mutex
+	calibrator hal.Calibrator
+	state      bool
+	value      float64
+	updated    time.Time
+	live       bool
 }
 
 func (p *pin) Close() error {
@@ -32,19 +42,42 @@ func (p *pin) Number() int {
 	return p.number
 }
 func (p *pin) Name() string {
+	if p.name != "" {
+		return p.name
+	}
 	return fmt.Sprintf("capability:%s pin:%d", p.cap.String(), p.number)
 }
 
-func (p *pin) Calibrate([]hal.Measurement) error {
-	return _notImplemented
+func (p *pin) Calibrate(points []hal.Measurement) error {
+	if p.cap != hal.AnalogInput {
+		return p.incompatibleCapability()
+	}
+	cal, err := hal.CalibratorFactory(points)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.calibrator = cal
+	p.mu.Unlock()
+	return nil
 }
 
 func (p *pin) Measure() (float64, error) {
-	return 0, _notImplemented
+	v, err := p.Value()
+	if err != nil {
+		return 0, err
+	}
+	p.mu.Lock()
+	cal := p.calibrator
+	p.mu.Unlock()
+	if cal == nil {
+		return 0, fmt.Errorf("Not calibrated")
+	}
+	return cal.Calibrate(v), nil
 }
 
 func (p *pin) doRequest(verb, url string, body io.Reader) (*http.Response, error) {
-	req, err := http.NewRequest(verb, url, body)
+	req, err := newRequest(verb, url, p.token, body)
 	if err != nil {
 		return nil, err
 	}
@@ -60,8 +93,105 @@ func (p *pin) readBody(body io.ReadCloser) ([]byte, error) {
 	return msg, nil
 }
 
+// LastState returns the cached output state, refreshing it from the firmware
+// once the cache is older than _stateTTL. If the firmware cannot be reached
+// the last known state is returned.
 func (p *pin) LastState() bool {
-	return false
+	p.mu.Lock()
+	fresh := time.Since(p.updated) < _stateTTL
+	state := p.state
+	p.mu.Unlock()
+	if fresh {
+		return state
+	}
+	s, err := p.State()
+	if err != nil {
+		return state
+	}
+	return s
+}
+
+// StateReader is implemented by ESP32 output pins and PWM channels to read
+// their current state back from the firmware.
+type StateReader interface {
+	// State reports whether the output is on
+	State() (bool, error)
+	// Get returns the duty cycle (0-100) of a PWM channel
+	Get() (float64, error)
+}
+
+// State queries the firmware for the current state of an output. PWM
+// channels are reported as on when their duty cycle is above zero.
+func (p *pin) State() (bool, error) {
+	switch p.cap {
+	case hal.DigitalOutput:
+		body, err := p.get(fmt.Sprintf("%s://%s/outlets/%d", p.scheme, p.address, p.number))
+		if err != nil {
+			return false, err
+		}
+		s := strings.ToLower(body) == _true
+		p.cacheState(s, 0)
+		return s, nil
+	case hal.PWM:
+		v, err := p.Get()
+		if err != nil {
+			return false, err
+		}
+		return v > 0, nil
+	default:
+		return false, p.incompatibleCapability()
+	}
+}
+
+// Get queries the firmware for the current duty cycle (0-100) of a PWM channel.
+func (p *pin) Get() (float64, error) {
+	if p.cap != hal.PWM {
+		return 0, p.incompatibleCapability()
+	}
+	body, err := p.get(fmt.Sprintf("%s://%s/jacks/%d", p.scheme, p.address, p.number))
+	if err != nil {
+		return 0, err
+	}
+	i, err := strconv.Atoi(body)
+	if err != nil {
+		return 0, fmt.Errorf("malformed jack value:'%s'", body)
+	}
+	v := mapFrom255(i)
+	p.cacheState(v > 0, v)
+	return v, nil
+}
+
+func (p *pin) cacheState(s bool, v float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.state = s
+	p.value = v
+	p.updated = time.Now()
+}
+
+func (p *pin) get(uri string) (string, error) {
+	resp, err := p.doRequest(http.MethodGet, uri, nil)
+	if err != nil {
+		return "", err
+	}
+	body, err := p.readBody(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read http response body. Error:%w", err)
+	}
+	if resp.StatusCode != 200 {
+		return "", fmt.Errorf("HTTP Code:%d. Body:%v", resp.StatusCode, string(body))
+	}
+	return strings.TrimSpace(string(body)), nil
+}
+
+func mapFrom255(i int) float64 {
+	if i < 0 {
+		i = 0
+	}
+	if i > 255 {
+		i = 255
+	}
+	return float64(i) * 100 / 255
 }
 
 func mapTo255String(f float64) int {
@@ -82,13 +212,14 @@ func (p *pin) Set(v float64) error {
 	if p.cap != hal.PWM {
 		return p.incompatibleCapability()
 	}
-	baseUri := "http://%s/jacks/%d/%d"
-	uri := fmt.Sprintf(baseUri, p.address, p.number, mapTo255String(v))
+	baseUri := "%s://%s/jacks/%d/%d"
+	uri := fmt.Sprintf(baseUri, p.scheme, p.address, p.number, mapTo255String(v))
 	resp, err := p.doRequest(http.MethodPost, uri, nil)
 	if err != nil {
 		return err
 	}
 	if resp.StatusCode == 200 {
+		p.cacheState(v > 0, v)
 		return nil
 	}
 	body, err := p.readBody(resp.Body)
@@ -106,13 +237,14 @@ func (p *pin) Write(b bool) error {
 	if b {
 		action = "on"
 	}
-	baseUri := "http://%s/outlets/%d/%s"
-	uri := fmt.Sprintf(baseUri, p.address, p.number, action)
+	baseUri := "%s://%s/outlets/%d/%s"
+	uri := fmt.Sprintf(baseUri, p.scheme, p.address, p.number, action)
 	resp, err := p.doRequest(http.MethodPost, uri, nil)
 	if err != nil {
 		return err
 	}
 	if resp.StatusCode == 200 {
+		p.cacheState(b, 0)
 		return nil
 	}
 	body, err := p.readBody(resp.Body)
@@ -126,8 +258,14 @@ func (p *pin) Read() (bool, error) {
 	if p.cap != hal.DigitalInput {
 		return false, p.incompatibleCapability()
 	}
-	baseUri := "http://%s/inlets/%d"
-	uri := fmt.Sprintf(baseUri, p.address, p.number)
+	p.mu.Lock()
+	live, state := p.live, p.state
+	p.mu.Unlock()
+	if live {
+		return state, nil
+	}
+	baseUri := "%s://%s/inlets/%d"
+	uri := fmt.Sprintf(baseUri, p.scheme, p.address, p.number)
 	resp, err := p.doRequest(http.MethodGet, uri, nil)
 	if err != nil {
 		return false, err
@@ -146,8 +284,8 @@ func (p *pin) Value() (float64, error) {
 	if p.cap != hal.AnalogInput {
 		return 0, p.incompatibleCapability()
 	}
-	baseUri := "http://%s/analog_inputs/%d"
-	uri := fmt.Sprintf(baseUri, p.address, p.number)
+	baseUri := "%s://%s/analog_inputs/%d"
+	uri := fmt.Sprintf(baseUri, p.scheme, p.address, p.number)
 	resp, err := p.doRequest(http.MethodGet, uri, nil)
 	if err != nil {
 		return 0, fmt.Errorf("failed to make http request. Error:%w", err)
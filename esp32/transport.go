@@ -0,0 +1,76 @@
+package esp32
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const _tokenHeader = "Authorization"
+
+var ErrFingerprintMismatch = errors.New("server certificate fingerprint mismatch")
+
+// parseFingerprint decodes a SHA-256 certificate fingerprint written as hex,
+// optionally separated by colons (as printed by openssl x509 -fingerprint).
+func parseFingerprint(s string) ([]byte, error) {
+	fp, err := hex.DecodeString(strings.ReplaceAll(strings.TrimSpace(s), ":", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid fingerprint. Error:%w", err)
+	}
+	if len(fp) != sha256.Size {
+		return nil, fmt.Errorf("invalid fingerprint length %d, expected %d bytes of SHA-256", len(fp), sha256.Size)
+	}
+	return fp, nil
+}
+
+// pinnedTLSConfig returns a TLS configuration that only accepts a server whose
+// leaf certificate matches the given SHA-256 fingerprint. Chain validation is
+// skipped so self-signed firmware certificates can be used.
+func pinnedTLSConfig(fingerprint []byte) *tls.Config {
+	return &tls.Config{
+		InsecureSkipVerify: true,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 {
+				return ErrFingerprintMismatch
+			}
+			sum := sha256.Sum256(cs.PeerCertificates[0].Raw)
+			if !bytes.Equal(sum[:], fingerprint) {
+				return fmt.Errorf("%w. received:%x", ErrFingerprintMismatch, sum)
+			}
+			return nil
+		},
+	}
+}
+
+// newHTTPClient builds the default client used when none was injected. When a
+// fingerprint is provided TLS connections are pinned to that certificate. A
+// zero timeout is used for the long-lived event stream.
+func newHTTPClient(fingerprint string, timeout time.Duration) (HTTPClient, error) {
+	c := &http.Client{Timeout: timeout}
+	if fingerprint != "" {
+		fp, err := parseFingerprint(fingerprint)
+		if err != nil {
+			return nil, err
+		}
+		c.Transport = &http.Transport{TLSClientConfig: pinnedTLSConfig(fp)}
+	}
+	return c.Do, nil
+}
+
+func newRequest(verb, url, token string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(verb, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set(_tokenHeader, "Bearer "+token)
+	}
+	return req, nil
+}
@@ -0,0 +1,94 @@
+package esp32
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/reef-pi/hal"
+)
+
+func TestESP32TokenAndScheme(t *testing.T) {
+	var seen []*http.Request
+	client := func(r *http.Request) (*http.Response, error) {
+		seen = append(seen, r)
+		resp := new(http.Response)
+		resp.StatusCode = 200
+		resp.Body = io.NopCloser(bytes.NewBufferString("true"))
+		return resp, nil
+	}
+	f := newFactory(client)
+	d, err := f.NewDriver(map[string]interface{}{
+		"Address":        "esp32.local",
+		"Digital-Output": 1,
+		"Digital-Input":  1,
+		"Pwm":            0,
+		"Analog-Input":   0,
+		"Token":          "s3cr3t",
+		"HTTPS":          true,
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, _ := d.(hal.DigitalOutputDriver).DigitalOutputPin(0)
+	if err := o.Write(true); err != nil {
+		t.Error(err)
+	}
+	if len(seen) != 1 {
+		t.Fatal("expected one request, found:", len(seen))
+	}
+	if seen[0].URL.Scheme != "https" {
+		t.Error("expected https scheme, found:", seen[0].URL.Scheme)
+	}
+	if h := seen[0].Header.Get("Authorization"); h != "Bearer s3cr3t" {
+		t.Error("unexpected authorization header:", h)
+	}
+	if _, err := f.NewDriver(map[string]interface{}{"Address": "esp32.local", "Fingerprint": "abcd"}, nil); err == nil {
+		t.Error("expected invalid fingerprint to fail validation")
+	}
+	fp := strings.Repeat("ab", sha256.Size)
+	if _, err := f.NewDriver(map[string]interface{}{"Address": "esp32.local", "Fingerprint": fp}, nil); err == nil {
+		t.Error("expected fingerprint with an injected client to fail closed")
+	}
+	if _, err := f.NewDriver(map[string]interface{}{"Address": "esp32.local", "HTTPS": "yes"}, nil); err == nil {
+		t.Error("expected non boolean HTTPS parameter to fail validation")
+	}
+}
+
+func TestESP32PinnedCertificate(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "true")
+	}))
+	defer srv.Close()
+	sum := sha256.Sum256(srv.Certificate().Raw)
+
+	c, err := newHTTPClient(fmt.Sprintf("%X", sum), _timeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/inlets/0", nil)
+	resp, err := c(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	var colons []string
+	for _, b := range sum {
+		colons = append(colons, fmt.Sprintf("%02x", b^0xff))
+	}
+	c, err = newHTTPClient(strings.Join(colons, ":"), _timeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ = http.NewRequest(http.MethodGet, srv.URL+"/inlets/0", nil)
+	if _, err := c(req); !errors.Is(err, ErrFingerprintMismatch) {
+		t.Error("expected fingerprint mismatch, found:", err)
+	}
+}
@@ -0,0 +1,156 @@
+package ezo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/reef-pi/rpi/i2c"
+)
+
+// DefaultAddresses are the factory I2C addresses of the EZO circuits
+var DefaultAddresses = []byte{
+	0x61, // DO
+	0x62, // ORP
+	0x63, // pH
+	0x64, // EC
+	0x66, // RTD
+	0x67, // PMP
+	0x69, // CO2
+	0x6A, // PRS
+	0x6F, // HUM
+}
+
+// CalibrationBackup holds the exported calibration of a single circuit
+type CalibrationBackup struct {
+	Address byte     `json:"address"`
+	Device  string   `json:"device"`
+	Version string   `json:"version"`
+	Data    []string `json:"data"`
+}
+
+// ExportCalibration returns the calibration of the circuit as a list of
+// strings that can later be passed to ImportCalibration.
+func (a *AtlasEZO) ExportCalibration() ([]string, error) {
+	// 10,120 : 10 strings, 120 bytes in total. Some firmware versions
+	// prefix the response with ?Export.
+	resp, err := a.query("Export,?")
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.Split(resp, ",")
+	if len(parts) < 2 || len(parts) > 3 {
+		return nil, fmt.Errorf("Malformed response:'%s'", resp)
+	}
+	n, err := strconv.Atoi(parts[len(parts)-2])
+	if err != nil {
+		return nil, fmt.Errorf("Malformed response:'%s'", resp)
+	}
+	var data []string
+	for i := 0; i < n; i++ {
+		s, err := a.query("Export")
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, s)
+	}
+	done, err := a.query("Export")
+	if err != nil {
+		return nil, err
+	}
+	if done != "*DONE" {
+		return nil, fmt.Errorf("Unexpected end of export:'%s'", done)
+	}
+	return data, nil
+}
+
+// ImportCalibration loads calibration strings produced by ExportCalibration.
+// The circuit reboots once the last string is imported.
+func (a *AtlasEZO) ImportCalibration(data []string) error {
+	for _, s := range data {
+		if err := a.command("Import," + s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ChangeAddress moves the circuit to a new I2C address. The circuit reboots
+// and only answers on the new address afterwards. A circuit in UART mode
+// switches to I2C mode.
+func (a *AtlasEZO) ChangeAddress(addr byte) error {
+	if addr < 1 || addr > 127 {
+		return fmt.Errorf("invalid I2C address:%d. It should be between 1 and 127", addr)
+	}
+	if err := a.command(fmt.Sprintf("I2C,%d", addr)); err != nil {
+		return err
+	}
+	if t, ok := a.transport.(*i2cTransport); ok {
+		t.addr = addr
+	}
+	return nil
+}
+
+// LockProtocol prevents the circuit from switching to UART mode
+func (a *AtlasEZO) LockProtocol() error {
+	return a.command("Plock,1")
+}
+
+func (a *AtlasEZO) UnlockProtocol() error {
+	return a.command("Plock,0")
+}
+
+func (a *AtlasEZO) ProtocolLocked() (bool, error) {
+	i, err := a.queryInt("Plock,?")
+	if err != nil {
+		return false, err
+	}
+	return i == 1, nil
+}
+
+// BackupCalibrations exports the calibration of every EZO circuit found at
+// the given addresses, or at DefaultAddresses when none are given. Addresses
+// without a responding circuit are skipped.
+func BackupCalibrations(bus i2c.Bus, addrs ...byte) ([]CalibrationBackup, error) {
+	if len(addrs) == 0 {
+		addrs = DefaultAddresses
+	}
+	var backups []CalibrationBackup
+	for _, addr := range addrs {
+		a := &AtlasEZO{transport: newI2CTransport(bus, addr)}
+		device, version, err := a.Information()
+		if err != nil {
+			continue
+		}
+		data, err := a.ExportCalibration()
+		if err != nil {
+			return nil, fmt.Errorf("failed to export calibration of %s circuit at 0x%X: %w", device, addr, err)
+		}
+		backups = append(backups, CalibrationBackup{
+			Address: addr,
+			Device:  device,
+			Version: version,
+			Data:    data,
+		})
+	}
+	return backups, nil
+}
+
+// RestoreCalibrations imports backed up calibrations, refusing to restore a
+// backup onto a circuit of a different type.
+func RestoreCalibrations(bus i2c.Bus, backups []CalibrationBackup) error {
+	for _, b := range backups {
+		a := &AtlasEZO{transport: newI2CTransport(bus, b.Address)}
+		device, _, err := a.Information()
+		if err != nil {
+			return fmt.Errorf("failed to identify circuit at 0x%X: %w", b.Address, err)
+		}
+		if !strings.EqualFold(device, b.Device) {
+			return fmt.Errorf("circuit at 0x%X is %s, backup is for %s", b.Address, device, b.Device)
+		}
+		if err := a.ImportCalibration(b.Data); err != nil {
+			return fmt.Errorf("failed to import calibration of %s circuit at 0x%X: %w", device, b.Address, err)
+		}
+	}
+	return nil
+}
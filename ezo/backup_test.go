@@ -0,0 +1,110 @@
+package ezo
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type fakeCircuit struct {
+	device   string
+	export   []string
+	next     int
+	imported []string
+	resp     string
+	// prefixed circuits answer Export,? with ?Export,n,size instead of n,size
+	prefixed bool
+}
+
+// circuitBus simulates several circuits, NACKing addresses without one
+type circuitBus struct {
+	fakeBus
+	circuits map[byte]*fakeCircuit
+}
+
+func (b *circuitBus) WriteBytes(addr byte, v []byte) error {
+	c, ok := b.circuits[addr]
+	if !ok {
+		return errors.New("remote I/O error")
+	}
+	cmd := string(v[:len(v)-1])
+	b.commands = append(b.commands, cmd)
+	switch {
+	case cmd == "i":
+		c.resp = fmt.Sprintf("?i,%s,2.16", c.device)
+	case cmd == "Export,?":
+		c.next = 0
+		c.resp = fmt.Sprintf("%d,24", len(c.export))
+		if c.prefixed {
+			c.resp = "?Export," + c.resp
+		}
+	case cmd == "Export":
+		c.resp = "*DONE"
+		if c.next < len(c.export) {
+			c.resp = c.export[c.next]
+			c.next++
+		}
+	case len(cmd) > 7 && cmd[:7] == "Import,":
+		c.imported = append(c.imported, cmd[7:])
+		c.resp = ""
+	default:
+		c.resp = ""
+	}
+	return nil
+}
+
+func (b *circuitBus) ReadBytes(addr byte, n int) ([]byte, error) {
+	c, ok := b.circuits[addr]
+	if !ok {
+		return nil, errors.New("remote I/O error")
+	}
+	buf := make([]byte, n)
+	buf[0] = codeSuccess
+	copy(buf[1:], c.resp)
+	return buf, nil
+}
+
+func TestEZOCalibrationBackup(t *testing.T) {
+	ph := &fakeCircuit{device: "pH", export: []string{"59 6F 75 20 61 72", "65 20 61 20 63 6F"}, prefixed: true}
+	ec := &fakeCircuit{device: "EC", export: []string{"6F 6C 20 67 75 79"}}
+	bus := &circuitBus{circuits: map[byte]*fakeCircuit{0x63: ph, 0x64: ec}}
+
+	backups, err := BackupCalibrations(bus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 {
+		t.Fatal("expected 2 backups, found:", len(backups))
+	}
+	if backups[0].Address != 0x63 || backups[0].Device != "pH" || len(backups[0].Data) != 2 {
+		t.Error("unexpected pH backup:", backups[0])
+	}
+	if backups[1].Data[0] != "6F 6C 20 67 75 79" {
+		t.Error("unexpected EC backup:", backups[1])
+	}
+
+	if err := RestoreCalibrations(bus, backups); err != nil {
+		t.Error(err)
+	}
+	if len(ph.imported) != 2 || ph.imported[1] != "65 20 61 20 63 6F" {
+		t.Error("unexpected pH import:", ph.imported)
+	}
+	backups[0].Device = "ORP"
+	if err := RestoreCalibrations(bus, backups); err == nil {
+		t.Error("expected restoring onto a different circuit type to fail")
+	}
+
+	a := &AtlasEZO{transport: newI2CTransport(bus, 0x63)}
+	if err := a.LockProtocol(); err != nil {
+		t.Error(err)
+	}
+	if err := a.ChangeAddress(0x70); err != nil {
+		t.Error(err)
+	}
+	if a.transport.(*i2cTransport).addr != 0x70 {
+		t.Error("expected address to follow the circuit")
+	}
+	if err := a.ChangeAddress(0x80); err == nil {
+		t.Error("expected out of range address to fail")
+	}
+}
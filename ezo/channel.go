@@ -0,0 +1,87 @@
+package ezo
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/reef-pi/hal"
+)
+
+// _readingTTL is how long a reading is shared between channels, so that the
+// values of all channels come from the same measurement
+const _readingTTL = time.Second
+
+// reading caches the last reading of a circuit for all of its channels
+type reading struct {
+	sync.Mutex
+	values []float64
+	readAt time.Time
+}
+
+// channel exposes one of the secondary readings of a multi output EZO
+// circuit, e.g. salinity on an EZO-EC. Channel 0 is the AtlasEZO itself.
+type channel struct {
+	ezo        *AtlasEZO
+	number     int
+	name       string
+	calibrator hal.Calibrator
+}
+
+func newChannel(a *AtlasEZO, i int, name string) (*channel, error) {
+	c, err := hal.CalibratorFactory([]hal.Measurement{})
+	if err != nil {
+		return nil, err
+	}
+	return &channel{
+		ezo:        a,
+		number:     i,
+		name:       name,
+		calibrator: c,
+	}, nil
+}
+
+func (c *channel) Name() string {
+	return c.name
+}
+
+func (c *channel) Number() int {
+	return c.number
+}
+
+func (c *channel) Calibrate(points []hal.Measurement) error {
+	cal, err := hal.CalibratorFactory(points)
+	if err != nil {
+		return err
+	}
+	c.calibrator = cal
+	return nil
+}
+
+// Value returns the channel from the latest reading of the circuit, shared by
+// all channels for up to a second
+func (c *channel) Value() (float64, error) {
+	vs, err := c.ezo.values(time.Now().Add(-_readingTTL))
+	if err != nil {
+		return 0, err
+	}
+	if len(vs) <= c.number {
+		return 0, fmt.Errorf("EZO response has no reading for channel %d. Enable output '%s'", c.number, c.name)
+	}
+	return vs[c.number], nil
+}
+
+func (c *channel) Measure() (float64, error) {
+	v, err := c.Value()
+	if err != nil {
+		return 0, err
+	}
+	if c.calibrator == nil {
+		return 0, fmt.Errorf("Not calibrated")
+	}
+	return c.calibrator.Calibrate(v), nil
+}
+
+func (c *channel) Close() error {
+	return nil
+}
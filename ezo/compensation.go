@@ -0,0 +1,45 @@
+package ezo
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/reef-pi/hal"
+)
+
+// SetTemperatureSource makes every reading of a pH, EC or DO circuit
+// compensated for the temperature measured by p, e.g. an SHT3x or DS18B20
+// channel. Pass nil to remove the source.
+func (a *AtlasEZO) SetTemperatureSource(p hal.AnalogInputPin) {
+	a.tempSource = p
+}
+
+// SetTemperature sets a fixed compensation temperature in °C, used when no
+// temperature source is configured or it fails to report.
+func (a *AtlasEZO) SetTemperature(t float64) {
+	a.temperature = t
+	a.fixedTemperature = true
+}
+
+// compensation returns the temperature to send along with a reading, and
+// false when the reading should not be compensated.
+func (a *AtlasEZO) compensation() (float64, bool) {
+	if a.spec == nil || !a.spec.compensated {
+		return 0, false
+	}
+	if a.tempSource != nil {
+		t, err := a.tempSource.Measure()
+		if err == nil {
+			return t, true
+		}
+		log.Println("ezo: failed to read compensation temperature from", a.tempSource.Name(), "Error:", err)
+	}
+	return a.temperature, a.fixedTemperature
+}
+
+func (a *AtlasEZO) readCommand() string {
+	if t, ok := a.compensation(); ok {
+		return fmt.Sprintf("RT,%.2f", t)
+	}
+	return "R"
+}
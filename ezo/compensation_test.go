@@ -0,0 +1,84 @@
+package ezo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/reef-pi/hal"
+)
+
+type fakeTemperature struct {
+	v   float64
+	err error
+}
+
+func (f *fakeTemperature) Name() string                      { return "water" }
+func (f *fakeTemperature) Number() int                       { return 0 }
+func (f *fakeTemperature) Close() error                      { return nil }
+func (f *fakeTemperature) Value() (float64, error)           { return f.v, f.err }
+func (f *fakeTemperature) Measure() (float64, error)         { return f.v, f.err }
+func (f *fakeTemperature) Calibrate([]hal.Measurement) error { return nil }
+
+func TestEZOTemperatureCompensation(t *testing.T) {
+	bus := newFakeBus(map[string]string{
+		"R":        "8.10",
+		"RT,18.50": "8.20",
+		"RT,22.30": "8.30",
+	})
+	a := &AtlasEZO{transport: newI2CTransport(bus, 0x63)}
+	spec, _ := lookupDevice("pH")
+	if err := a.init(spec); err != nil {
+		t.Fatal(err)
+	}
+	read := func(expected float64) {
+		t.Helper()
+		v, err := a.Value()
+		if err != nil {
+			t.Error(err)
+		}
+		if v != expected {
+			t.Error("expected", expected, "found:", v, "last command:", bus.last)
+		}
+	}
+	read(8.10)
+	a.SetTemperature(18.5)
+	read(8.20)
+	source := &fakeTemperature{v: 22.3}
+	a.SetTemperatureSource(source)
+	read(8.30)
+	source.err = errors.New("sensor unplugged")
+	read(8.20)
+
+	orp := &AtlasEZO{transport: newI2CTransport(bus, 0x62)}
+	spec, _ = lookupDevice("ORP")
+	if err := orp.init(spec); err != nil {
+		t.Fatal(err)
+	}
+	orp.SetTemperature(18.5)
+	if _, err := orp.Value(); err != nil {
+		t.Error(err)
+	}
+	if bus.last != "R" {
+		t.Error("ORP readings should not be compensated, sent:", bus.last)
+	}
+}
+
+func TestEZOFixedTemperatureParameter(t *testing.T) {
+	for temp, cmd := range map[float64]string{0: "R", 18.5: "RT,18.50"} {
+		bus := newFakeBus(map[string]string{"R": "8.10", "RT,18.50": "8.20"})
+		d, err := Factory().NewDriver(map[string]interface{}{
+			"Address":     0x63,
+			"Type":        "pH",
+			"Temperature": temp,
+		}, bus)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := d.(*AtlasEZO).Value(); err != nil {
+			t.Error(err)
+		}
+		if bus.last != cmd {
+			t.Error("expected temperature", temp, "to send", cmd, "found:", bus.last)
+		}
+	}
+}
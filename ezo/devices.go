@@ -0,0 +1,186 @@
+package ezo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/reef-pi/hal"
+)
+
+/*
+https://atlas-scientific.com/embedded-solutions/
+Each EZO circuit reports its type in the "i" information response, e.g.
+?i,EC,2.10. Circuits with several outputs return comma separated readings
+in the order of their enabled outputs.
+*/
+
+type calibrateFunc func(*AtlasEZO, []hal.Measurement) error
+
+type device struct {
+	code        string
+	description string
+	// channels lists the readings returned by "R", in order
+	channels []string
+	// outputs are sent on initialization to enable every reading in channels
+	outputs []string
+	// compensated circuits support the RT temperature compensated reading
+	compensated bool
+	// calibrate performs on-circuit calibration. Circuits without one are
+	// calibrated in software using hal.Calibrator.
+	calibrate calibrateFunc
+}
+
+func (d *device) name() string {
+	return fmt.Sprintf("Atlas Scientific EZO(%s)", d.code)
+}
+
+var devices = []*device{
+	{
+		code:        "pH",
+		compensated: true,
+		description: "Atlas Scientific EZO board for pH sensor",
+		channels:    []string{"pH"},
+		calibrate:   phCalibrate,
+	},
+	{
+		code:        "EC",
+		compensated: true,
+		description: "Atlas Scientific EZO board for conductivity sensor",
+		channels:    []string{"conductivity", "tds", "salinity", "specific gravity"},
+		outputs:     []string{"O,EC,1", "O,TDS,1", "O,S,1", "O,SG,1"},
+		calibrate:   ecCalibrate,
+	},
+	{
+		code:        "ORP",
+		description: "Atlas Scientific EZO board for oxidation reduction potential sensor",
+		channels:    []string{"orp"},
+		calibrate:   singlePointCalibrate,
+	},
+	{
+		code:        "DO",
+		compensated: true,
+		description: "Atlas Scientific EZO board for dissolved oxygen sensor",
+		channels:    []string{"dissolved oxygen", "saturation"},
+		outputs:     []string{"O,mg,1", "O,%,1"},
+		calibrate:   doCalibrate,
+	},
+	{
+		code:        "RTD",
+		description: "Atlas Scientific EZO board for temperature sensor",
+		channels:    []string{"temperature"},
+		calibrate:   singlePointCalibrate,
+	},
+	{
+		code:        "PRS",
+		description: "Atlas Scientific EZO board for pressure sensor",
+		channels:    []string{"pressure"},
+		calibrate:   prsCalibrate,
+	},
+	{
+		code:        "HUM",
+		description: "Atlas Scientific EZO board for humidity sensor",
+		channels:    []string{"humidity", "temperature", "dew point"},
+		outputs:     []string{"O,HUM,1", "O,T,1", "O,Dew,1"},
+	},
+	{
+		code:        "CO2",
+		description: "Atlas Scientific EZO board for carbon dioxide sensor",
+		channels:    []string{"co2"},
+	},
+}
+
+func lookupDevice(code string) (*device, error) {
+	for _, d := range devices {
+		if strings.EqualFold(d.code, code) {
+			return d, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported EZO device type:'%s'", code)
+}
+
+func phCalibrate(a *AtlasEZO, ms []hal.Measurement) error {
+	for _, m := range ms {
+		switch m.Expected {
+		case 10:
+			if err := a.CalibrateHigh(m.Observed); err != nil {
+				return err
+			}
+		case 7:
+			if err := a.CalibrateMid(m.Observed); err != nil {
+				return err
+			}
+		case 4:
+			if err := a.CalibrateLow(m.Observed); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("Expected calibration value %f is not supported", m.Expected)
+		}
+	}
+	return nil
+}
+
+// ecCalibrate performs dry calibration for an expected value of 0, followed by
+// single point or low/high two point calibration.
+func ecCalibrate(a *AtlasEZO, ms []hal.Measurement) error {
+	var points []float64
+	for _, m := range ms {
+		if m.Expected == 0 {
+			if err := a.command("Cal,dry"); err != nil {
+				return err
+			}
+			continue
+		}
+		points = append(points, m.Expected)
+	}
+	sort.Float64s(points)
+	switch len(points) {
+	case 0:
+		return nil
+	case 1:
+		return a.command(fmt.Sprintf("Cal,%g", points[0]))
+	case 2:
+		if err := a.command(fmt.Sprintf("Cal,low,%g", points[0])); err != nil {
+			return err
+		}
+		return a.command(fmt.Sprintf("Cal,high,%g", points[1]))
+	default:
+		return fmt.Errorf("EC calibration supports at most two non zero points. Found:%d", len(points))
+	}
+}
+
+// doCalibrate calibrates to atmospheric oxygen, or to zero dissolved oxygen
+// for an expected value of 0.
+func doCalibrate(a *AtlasEZO, ms []hal.Measurement) error {
+	for _, m := range ms {
+		cmd := "Cal"
+		if m.Expected == 0 {
+			cmd = "Cal,0"
+		}
+		if err := a.command(cmd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func prsCalibrate(a *AtlasEZO, ms []hal.Measurement) error {
+	for _, m := range ms {
+		cmd := fmt.Sprintf("Cal,high,%g", m.Expected)
+		if m.Expected == 0 {
+			cmd = "Cal,0"
+		}
+		if err := a.command(cmd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func singlePointCalibrate(a *AtlasEZO, ms []hal.Measurement) error {
+	if len(ms) != 1 {
+		return fmt.Errorf("%s calibration requires exactly one point. Found:%d", a.spec.code, len(ms))
+	}
+	return a.command(fmt.Sprintf("Cal,%g", ms[0].Expected))
+}
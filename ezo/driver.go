@@ -7,7 +7,6 @@ import (
 	"time"
 
 	"github.com/reef-pi/hal"
-	"github.com/reef-pi/rpi/i2c"
 )
 
 /*
@@ -19,54 +18,75 @@ const (
 )
 
 type AtlasEZO struct {
-	addr  byte
-	bus   i2c.Bus
-	delay time.Duration
-	meta  hal.Metadata
+	transport  Transport
+	meta       hal.Metadata
+	spec       *device
+	channels   []hal.AnalogInputPin
+	calibrator hal.Calibrator
+	last       reading
+
+	tempSource       hal.AnalogInputPin
+	temperature      float64
+	fixedTemperature bool
 }
 
-func (a *AtlasEZO) extractIntResponse() (int, error) {
-	resp, err := a.read()
+// New returns a driver for the circuit behind t. kind is a circuit type such
+// as "pH" or "EC", or "auto" to detect it from the information response.
+func New(t Transport, kind string) (*AtlasEZO, error) {
+	a := &AtlasEZO{transport: t}
+	var spec *device
+	var err error
+	if kind == autoDetect {
+		spec, err = a.Detect()
+	} else {
+		spec, err = lookupDevice(kind)
+	}
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	parts := strings.Split(resp, ",")
-	if len(parts) != 2 {
-		return 0, fmt.Errorf("Malformed response:'%s'", resp)
+	if err := a.init(spec); err != nil {
+		return nil, err
 	}
-	return strconv.Atoi(parts[1])
+	return a, nil
 }
 
-func (a *AtlasEZO) extractFloatResponse() (float64, error) {
-	resp, err := a.read()
+// init configures the driver for the given circuit type, enabling all of its
+// outputs and creating one channel per reading.
+func (a *AtlasEZO) init(spec *device) error {
+	a.spec = spec
+	a.meta = hal.Metadata{
+		Name:         spec.name(),
+		Description:  spec.description,
+		Capabilities: []hal.Capability{hal.AnalogInput},
+	}
+	cal, err := hal.CalibratorFactory([]hal.Measurement{})
 	if err != nil {
-		return 0, err
+		return err
 	}
-	parts := strings.Split(resp, ",")
-	if len(parts) != 2 {
-		return 0, fmt.Errorf("Malformed response:'%s'", resp)
+	a.calibrator = cal
+	for _, o := range spec.outputs {
+		if err := a.command(o); err != nil {
+			return err
+		}
 	}
-	return strconv.ParseFloat(parts[1], 64)
-}
-
-func (a *AtlasEZO) command(cmd string) error {
-	if err := a.bus.WriteBytes(a.addr, []byte(cmd+"\000")); err != nil {
-		return err
+	a.channels = []hal.AnalogInputPin{a}
+	for i, name := range spec.channels[1:] {
+		ch, err := newChannel(a, i+1, name)
+		if err != nil {
+			return err
+		}
+		a.channels = append(a.channels, ch)
 	}
-	time.Sleep(a.delay)
 	return nil
 }
 
-func (a *AtlasEZO) read() (string, error) {
-	payload, err := a.bus.ReadBytes(a.addr, 31)
+// Detect identifies the circuit type from its information response.
+func (a *AtlasEZO) Detect() (*device, error) {
+	code, _, err := a.Information()
 	if err != nil {
-		return "", err
-	}
-	if payload[0] != byte(1) {
-		return "", fmt.Errorf("Failed to execute. Error:%s", string(payload))
+		return nil, err
 	}
-	p := strings.Trim(string(payload[1:]), "\000")
-	return p, nil
+	return lookupDevice(code)
 }
 
 func (a *AtlasEZO) LedOn() error {
@@ -78,10 +98,7 @@ func (a *AtlasEZO) LedOff() error {
 }
 
 func (a *AtlasEZO) LedState() (bool, error) {
-	if err := a.command("L,?"); err != nil {
-		return false, err
-	}
-	i, err := a.extractIntResponse()
+	i, err := a.queryInt("L,?")
 	if err != nil {
 		return false, err
 	}
@@ -93,27 +110,15 @@ func (a *AtlasEZO) Baud(n int) error {
 }
 
 func (a *AtlasEZO) CalibrateMid(n float64) error {
-	if err := a.command(fmt.Sprintf("Cal,mid,%f", n)); err != nil {
-		return err
-	}
-	time.Sleep(600 * time.Millisecond)
-	return nil
+	return a.command(fmt.Sprintf("Cal,mid,%f", n))
 }
 
 func (a *AtlasEZO) CalibrateHigh(n float64) error {
-	if err := a.command(fmt.Sprintf("Cal,high,%f", n)); err != nil {
-		return err
-	}
-	time.Sleep(600 * time.Millisecond)
-	return nil
+	return a.command(fmt.Sprintf("Cal,high,%f", n))
 }
 
 func (a *AtlasEZO) CalibrateLow(n float64) error {
-	if err := a.command(fmt.Sprintf("Cal,low,%f", n)); err != nil {
-		return err
-	}
-	time.Sleep(600 * time.Millisecond)
-	return nil
+	return a.command(fmt.Sprintf("Cal,low,%f", n))
 }
 
 func (a *AtlasEZO) ClearCalibration() error {
@@ -121,10 +126,7 @@ func (a *AtlasEZO) ClearCalibration() error {
 }
 
 func (a *AtlasEZO) IsCalibrated() (int, error) {
-	if err := a.command("Cal,?"); err != nil {
-		return 0, err
-	}
-	return a.extractIntResponse()
+	return a.queryInt("Cal,?")
 }
 
 func (a *AtlasEZO) Factory() error {
@@ -136,10 +138,7 @@ func (a *AtlasEZO) Find() error {
 }
 
 func (a *AtlasEZO) Information() (string, string, error) {
-	if err := a.command("i"); err != nil {
-		return "", "", err
-	}
-	resp, err := a.read()
+	resp, err := a.query("i")
 	if err != nil {
 		return "", "", err
 	}
@@ -151,14 +150,46 @@ func (a *AtlasEZO) Information() (string, string, error) {
 }
 
 func (a *AtlasEZO) Value() (float64, error) {
-	if err := a.command("R"); err != nil {
+	vs, err := a.Values()
+	if err != nil {
 		return 0, err
 	}
-	v, err := a.read()
+	return vs[0], nil
+}
+
+// Values takes a reading, temperature compensated when possible, and returns
+// every numeric field of the response in the order of the circuit's enabled
+// outputs. Labels such as the "Dew" marker of EZO-HUM are skipped. Concurrent
+// callers share the reading in progress.
+func (a *AtlasEZO) Values() ([]float64, error) {
+	return a.values(time.Now())
+}
+
+// values returns the last reading if it was taken after since, and takes a
+// new one otherwise. Concurrent callers wait for the reading in progress.
+func (a *AtlasEZO) values(since time.Time) ([]float64, error) {
+	a.last.Lock()
+	defer a.last.Unlock()
+	if a.last.readAt.After(since) {
+		return a.last.values, nil
+	}
+	resp, err := a.query(a.readCommand())
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+	var vs []float64
+	for _, f := range strings.Split(resp, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+		if err != nil {
+			continue
+		}
+		vs = append(vs, v)
 	}
-	return strconv.ParseFloat(v, 64)
+	if len(vs) == 0 {
+		return nil, fmt.Errorf("Malformed response:'%s'", resp)
+	}
+	a.last.values, a.last.readAt = vs, time.Now()
+	return vs, nil
 }
 
 func (a *AtlasEZO) Sleep() error {
@@ -166,11 +197,8 @@ func (a *AtlasEZO) Sleep() error {
 }
 
 func (a *AtlasEZO) Status() (string, string, error) {
-	if err := a.command("Status"); err != nil {
-		return "", "", err
-	}
 	//?Status,P,5.038
-	resp, err := a.read()
+	resp, err := a.query("Status")
 	if err != nil {
 		return "", "", err
 	}
@@ -182,10 +210,7 @@ func (a *AtlasEZO) Status() (string, string, error) {
 }
 
 func (a *AtlasEZO) GetTC() (float64, error) {
-	if err := a.command("T,?"); err != nil {
-		return 0, err
-	}
-	return a.extractFloatResponse()
+	return a.queryFloat("T,?")
 }
 
 func (a *AtlasEZO) SetTC(t float64) error {
@@ -193,7 +218,7 @@ func (a *AtlasEZO) SetTC(t float64) error {
 }
 
 func (a *AtlasEZO) Name() string {
-	return _ezoName
+	return a.spec.name()
 }
 
 func (a *AtlasEZO) Close() error {
@@ -204,46 +229,50 @@ func (a *AtlasEZO) Metadata() hal.Metadata {
 	return a.meta
 }
 
+// Calibrate performs on-circuit calibration for circuits that support it,
+// falling back to software calibration otherwise.
 func (a *AtlasEZO) Calibrate(ms []hal.Measurement) error {
-	for _, m := range ms {
-		switch m.Expected {
-		case 10:
-			if err := a.CalibrateHigh(m.Observed); err != nil {
-				return err
-			}
-		case 7:
-			if err := a.CalibrateMid(m.Observed); err != nil {
-				return err
-			}
-		case 4:
-			if err := a.CalibrateLow(m.Observed); err != nil {
-				return err
-			}
-		default:
-			return fmt.Errorf("Expected calibration value %f is not supported", m.Expected)
-		}
+	if a.spec.calibrate != nil {
+		return a.spec.calibrate(a, ms)
 	}
+	cal, err := hal.CalibratorFactory(ms)
+	if err != nil {
+		return err
+	}
+	a.calibrator = cal
 	return nil
 }
+
 func (a *AtlasEZO) Measure() (float64, error) {
-	return a.Value()
+	v, err := a.Value()
+	if err != nil {
+		return 0, err
+	}
+	if a.calibrator == nil {
+		return v, nil
+	}
+	return a.calibrator.Calibrate(v), nil
 }
 
 func (a *AtlasEZO) AnalogInputPin(u int) (hal.AnalogInputPin, error) {
-	if u != 0 {
-		return nil, fmt.Errorf("EZO pH driver has only one valid channel: 0. Asked:%d", u)
+	if u < 0 || u >= len(a.channels) {
+		return nil, fmt.Errorf("%s driver has %d channels. Asked:%d", a.Name(), len(a.channels), u)
 	}
-	return a, nil
+	return a.channels[u], nil
 }
 
 func (a *AtlasEZO) AnalogInputPins() []hal.AnalogInputPin {
-	return []hal.AnalogInputPin{a}
+	return a.channels
 }
 
 func (a *AtlasEZO) Pins(cap hal.Capability) ([]hal.Pin, error) {
 	switch cap {
 	case hal.AnalogInput:
-		return []hal.Pin{a}, nil
+		pins := make([]hal.Pin, len(a.channels))
+		for i, ch := range a.channels {
+			pins[i] = ch
+		}
+		return pins, nil
 	default:
 		return nil, fmt.Errorf("unsupported capability:%s", cap.String())
 	}
@@ -1,13 +1,22 @@
 package ezo
 
 import (
+	"os"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/reef-pi/hal"
 
 	"github.com/reef-pi/rpi/i2c"
 )
 
+func TestMain(m *testing.M) {
+	sleep = func(time.Duration) {}
+	os.Exit(m.Run())
+}
+
 func TestEZO(t *testing.T) {
 	factory := Factory()
 	params := map[string]interface{}{
@@ -27,7 +36,7 @@ func TestEZO(t *testing.T) {
 		t.Error("Unable to convert driver to AtlasEZO")
 	}
 
-	e.delay = 0
+	e.transport.(*i2cTransport).delay = 0
 	bus.Bytes = append([]byte{1}, []byte("9.65")...)
 	if _, err := e.Value(); err != nil {
 		t.Error(err)
@@ -117,3 +126,147 @@ func TestEZOHalAdapter(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+// fakeBus answers EZO commands from a table of responses keyed by command.
+// Commands without a response succeed without data. Codes queued for a
+// command are returned, one per read, before its response.
+type fakeBus struct {
+	responses map[string]string
+	codes     map[string][]byte
+	commands  []string
+	reads     int
+	last      string
+}
+
+func newFakeBus(responses map[string]string) *fakeBus {
+	return &fakeBus{responses: responses, codes: make(map[string][]byte)}
+}
+
+func (b *fakeBus) SetAddress(_ byte) error { return nil }
+func (b *fakeBus) ReadBytes(_ byte, n int) ([]byte, error) {
+	b.reads++
+	buf := make([]byte, n)
+	if codes := b.codes[b.last]; len(codes) > 0 {
+		buf[0] = codes[0]
+		b.codes[b.last] = codes[1:]
+		return buf, nil
+	}
+	buf[0] = codeSuccess
+	copy(buf[1:], b.responses[b.last])
+	return buf, nil
+}
+func (b *fakeBus) WriteBytes(_ byte, v []byte) error {
+	b.last = strings.TrimRight(string(v), "\000")
+	b.commands = append(b.commands, b.last)
+	return nil
+}
+func (b *fakeBus) ReadFromReg(_, _ byte, _ []byte) error { return nil }
+func (b *fakeBus) WriteToReg(_, _ byte, _ []byte) error  { return nil }
+func (b *fakeBus) Close() error                          { return nil }
+
+func TestEZOFamily(t *testing.T) {
+	bus := newFakeBus(map[string]string{
+		"i": "?i,EC,2.10",
+		"R": "53000,26500,34.95,1.025",
+	})
+	a := &AtlasEZO{transport: newI2CTransport(bus, 0x64)}
+	spec, err := a.Detect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.init(spec); err != nil {
+		t.Fatal(err)
+	}
+	if a.Name() != "Atlas Scientific EZO(EC)" {
+		t.Error("unexpected name:", a.Name())
+	}
+	for _, o := range []string{"O,EC,1", "O,TDS,1", "O,S,1", "O,SG,1"} {
+		if !contains(bus.commands, o) {
+			t.Error("expected output to be enabled:", o)
+		}
+	}
+	pins := a.AnalogInputPins()
+	if len(pins) != 4 {
+		t.Fatal("expected 4 channels, found:", len(pins))
+	}
+	expected := []float64{53000, 26500, 34.95, 1.025}
+	bus.commands = nil
+	var wg sync.WaitGroup
+	for i, p := range pins {
+		wg.Add(1)
+		go func(i int, p hal.AnalogInputPin) {
+			defer wg.Done()
+			v, err := p.Value()
+			if err != nil {
+				t.Error(err)
+			}
+			if v != expected[i] {
+				t.Error("channel", p.Name(), "expected", expected[i], "found:", v)
+			}
+		}(i, p)
+	}
+	wg.Wait()
+	// channel 0 only reuses a reading taken after it was called
+	if reads := strings.Count(strings.Join(bus.commands, " "), "R"); reads > 2 {
+		t.Error("expected channels to share a reading, found commands:", bus.commands)
+	}
+	bus.commands = nil
+	for _, p := range pins[1:] {
+		p.Value()
+	}
+	if len(bus.commands) != 0 {
+		t.Error("expected secondary channels to reuse the last reading, found commands:", bus.commands)
+	}
+	if pins[2].Name() != "salinity" {
+		t.Error("unexpected channel name:", pins[2].Name())
+	}
+	bus.commands = nil
+	if err := a.Calibrate([]hal.Measurement{{Expected: 0}, {Expected: 80000}, {Expected: 12880}}); err != nil {
+		t.Error(err)
+	}
+	for _, c := range []string{"Cal,dry", "Cal,low,12880", "Cal,high,80000"} {
+		if !contains(bus.commands, c) {
+			t.Error("expected calibration command:", c, "found:", bus.commands)
+		}
+	}
+
+	bus = newFakeBus(map[string]string{
+		"i": "?i,HUM,1.0",
+		"R": "48.25,22.10,Dew,10.50",
+	})
+	a = &AtlasEZO{transport: newI2CTransport(bus, 0x6F)}
+	spec, err = a.Detect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.init(spec); err != nil {
+		t.Fatal(err)
+	}
+	dew, err := a.AnalogInputPin(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := dew.Value(); v != 10.5 {
+		t.Error("expected dew point 10.5, found:", v)
+	}
+	if err := a.Calibrate([]hal.Measurement{{Expected: 50, Observed: 48.25}}); err != nil {
+		t.Error(err)
+	}
+	if v, _ := a.Measure(); v != 50 {
+		t.Error("expected software calibrated humidity 50, found:", v)
+	}
+
+	a = &AtlasEZO{transport: newI2CTransport(newFakeBus(map[string]string{"i": "?i,XYZ,1.0"}), 0x62)}
+	if _, err := a.Detect(); err == nil {
+		t.Error("expected unknown device type to fail detection")
+	}
+}
+
+func contains(l []string, s string) bool {
+	for _, e := range l {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package ezo
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const _defaultProcessingTime = 300 * time.Millisecond
+
+var (
+	ErrSyntax  = errors.New("ezo: syntax error")
+	ErrNoData  = errors.New("ezo: no data to send")
+	ErrPending = errors.New("ezo: still processing")
+	ErrTimeout = errors.New("ezo: no response")
+)
+
+// processingTimes lists commands that take longer than the default 300ms,
+// keyed by lower case command name.
+var processingTimes = map[string]time.Duration{
+	"r":   900 * time.Millisecond,
+	"rt":  900 * time.Millisecond,
+	"cal": 900 * time.Millisecond,
+}
+
+// writeOnly lists commands after which the circuit sleeps or reboots and
+// never answers.
+var writeOnly = map[string]bool{
+	"sleep":   true,
+	"factory": true,
+	"i2c":     true,
+	"baud":    true,
+}
+
+func commandName(cmd string) string {
+	return strings.ToLower(strings.SplitN(cmd, ",", 2)[0])
+}
+
+func processingTime(cmd string) time.Duration {
+	if d, ok := processingTimes[commandName(cmd)]; ok {
+		return d
+	}
+	return _defaultProcessingTime
+}
+
+// Transport carries commands to an EZO circuit and returns its responses
+type Transport interface {
+	// Query sends cmd and returns the response data once processing is done
+	Query(cmd string) (string, error)
+	// Send sends cmd without waiting for response data
+	Send(cmd string) error
+}
+
+// query sends cmd and returns the response data. Commands after which the
+// circuit sleeps or reboots are sent without waiting for response data.
+func (a *AtlasEZO) query(cmd string) (string, error) {
+	if writeOnly[commandName(cmd)] {
+		return "", a.transport.Send(cmd)
+	}
+	resp, err := a.transport.Query(cmd)
+	if err != nil {
+		return "", fmt.Errorf("command '%s' failed: %w", cmd, err)
+	}
+	return resp, nil
+}
+
+func (a *AtlasEZO) command(cmd string) error {
+	_, err := a.query(cmd)
+	return err
+}
+
+func (a *AtlasEZO) queryInt(cmd string) (int, error) {
+	resp, err := a.query(cmd)
+	if err != nil {
+		return 0, err
+	}
+	parts := strings.Split(resp, ",")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("Malformed response:'%s'", resp)
+	}
+	return strconv.Atoi(parts[1])
+}
+
+func (a *AtlasEZO) queryFloat(cmd string) (float64, error) {
+	resp, err := a.query(cmd)
+	if err != nil {
+		return 0, err
+	}
+	parts := strings.Split(resp, ",")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("Malformed response:'%s'", resp)
+	}
+	return strconv.ParseFloat(parts[1], 64)
+}
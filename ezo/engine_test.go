@@ -0,0 +1,72 @@
+package ezo
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestEZOCommandEngine(t *testing.T) {
+	bus := newFakeBus(map[string]string{
+		"R":   "7.012",
+		"T,?": "?T,25.0",
+	})
+	var slept []time.Duration
+	defer func(s func(time.Duration)) { sleep = s }(sleep)
+	sleep = func(d time.Duration) { slept = append(slept, d) }
+	a := &AtlasEZO{transport: newI2CTransport(bus, 0x63)}
+
+	// a reading waits 900ms, then polls while the circuit answers 254
+	bus.codes["R"] = []byte{codePending, codePending}
+	v, err := a.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != 7.012 {
+		t.Error("expected 7.012, found:", v)
+	}
+	expected := []time.Duration{900 * time.Millisecond, _pollInterval, _pollInterval}
+	if len(slept) != len(expected) {
+		t.Fatal("expected waits", expected, "found:", slept)
+	}
+	for i, d := range expected {
+		if slept[i] != d {
+			t.Error("expected wait", d, "found:", slept[i])
+		}
+	}
+
+	slept = nil
+	if _, err := a.GetTC(); err != nil {
+		t.Error(err)
+	}
+	if len(slept) != 1 || slept[0] != _defaultProcessingTime {
+		t.Error("expected a single default wait, found:", slept)
+	}
+
+	bus.codes["L,3"] = []byte{codeSyntaxError}
+	if err := a.command("L,3"); !errors.Is(err, ErrSyntax) {
+		t.Error("expected syntax error, found:", err)
+	}
+
+	bus.codes["R"] = []byte{codeNoData}
+	if _, err := a.Value(); !errors.Is(err, ErrNoData) {
+		t.Error("expected no data error, found:", err)
+	}
+
+	pending := make([]byte, _maxPolls)
+	for i := range pending {
+		pending[i] = codePending
+	}
+	bus.codes["Cal,mid,7.00"] = pending
+	if err := a.command("Cal,mid,7.00"); !errors.Is(err, ErrPending) {
+		t.Error("expected still processing error, found:", err)
+	}
+
+	bus.reads = 0
+	if err := a.Sleep(); err != nil {
+		t.Error(err)
+	}
+	if bus.reads != 0 {
+		t.Error("sleep command should not wait for a response")
+	}
+}
@@ -3,9 +3,10 @@ package ezo
 import (
 	"errors"
 	"fmt"
+	"io"
 	"sync"
-	"time"
 
+	"github.com/reef-pi/drivers/internal/convert"
 	"github.com/reef-pi/hal"
 	"github.com/reef-pi/rpi/i2c"
 )
@@ -15,7 +16,12 @@ type factory struct {
 	parameters []hal.ConfigParameter
 }
 
-const addressParam = "Address"
+const (
+	addressParam = "Address"
+	typeParam    = "Type"
+	tempParam    = "Temperature"
+	autoDetect   = "auto"
+)
 
 var ezoFactory *factory
 var once sync.Once
@@ -27,7 +33,7 @@ func Factory() hal.DriverFactory {
 		ezoFactory = &factory{
 			meta: hal.Metadata{
 				Name:         _ezoName,
-				Description:  "Atlas Scientific EZO board for pH sensor",
+				Description:  "Atlas Scientific EZO boards for pH, EC, ORP, DO, RTD, PRS, HUM and CO2 sensors",
 				Capabilities: []hal.Capability{hal.AnalogInput},
 			},
 			parameters: []hal.ConfigParameter{
@@ -37,6 +43,18 @@ func Factory() hal.DriverFactory {
 					Order:   0,
 					Default: 68,
 				},
+				{
+					Name:    typeParam,
+					Type:    hal.String,
+					Order:   1,
+					Default: autoDetect,
+				},
+				{
+					Name:    tempParam,
+					Type:    hal.Decimal,
+					Order:   2,
+					Default: 0.0,
+				},
 			},
 		}
 	})
@@ -48,7 +66,7 @@ func (f *factory) Metadata() hal.Metadata {
 	return f.meta
 }
 
-//Implement hal.Driver interface
+// Implement hal.Driver interface
 func (f *factory) GetParameters() []hal.ConfigParameter {
 	return f.parameters
 }
@@ -72,25 +90,65 @@ func (f *factory) ValidateParameters(parameters map[string]interface{}) (bool, m
 		failures[addressParam] = append(failures[addressParam], failure)
 	}
 
+	if v, ok := parameters[typeParam]; ok {
+		t, ok := v.(string)
+		if !ok {
+			failure := fmt.Sprint(typeParam, " is not a string. ", v, " was received.")
+			failures[typeParam] = append(failures[typeParam], failure)
+		} else if t != autoDetect {
+			if _, err := lookupDevice(t); err != nil {
+				failures[typeParam] = append(failures[typeParam], err.Error())
+			}
+		}
+	}
+
+	if v, ok := parameters[tempParam]; ok {
+		if _, ok := convert.ToFloat(v); !ok {
+			failure := fmt.Sprint(tempParam, " is not a number. ", v, " was received.")
+			failures[tempParam] = append(failures[tempParam], failure)
+		}
+	}
+
 	return len(failures) == 0, failures
 }
 
+// transportFor returns an I2C transport for an i2c.Bus and a UART transport
+// for any other io.ReadWriter, such as a serial port.
+func transportFor(address int, hardwareResources interface{}) (Transport, error) {
+	switch r := hardwareResources.(type) {
+	case i2c.Bus:
+		return newI2CTransport(r, byte(address)), nil
+	case io.ReadWriter:
+		return NewSerialTransport(r), nil
+	default:
+		return nil, fmt.Errorf("unsupported hardware resource %T. Expected i2c.Bus or io.ReadWriter", hardwareResources)
+	}
+}
+
 func (f *factory) NewDriver(parameters map[string]interface{}, hardwareResources interface{}) (hal.Driver, error) {
 	if valid, failures := f.ValidateParameters(parameters); !valid {
 		return nil, errors.New(hal.ToErrorString(failures))
 	}
 
 	address, _ := hal.ConvertToInt(parameters[addressParam])
+	t, err := transportFor(address, hardwareResources)
+	if err != nil {
+		return nil, err
+	}
 
-	driver := &AtlasEZO{
-		addr:  byte(address),
-		bus:   hardwareResources.(i2c.Bus),
-		delay: time.Second,
-		meta: hal.Metadata{
-			Name:         _ezoName,
-			Description:  "Atlas Scientific EZO board for pH sensor",
-			Capabilities: []hal.Capability{hal.AnalogInput},
-		},
+	// Drivers configured before other circuits were supported are pH
+	device := "pH"
+	if v, ok := parameters[typeParam]; ok {
+		device = v.(string)
+	}
+	driver, err := New(t, device)
+	if err != nil {
+		return nil, err
+	}
+	// A fixed temperature of 0 leaves compensation to the temperature
+	// stored on the circuit
+	if t, _ := convert.ToFloat(parameters[tempParam]); t != 0 {
+		driver.SetTemperature(t)
 	}
 
 	return driver, nil
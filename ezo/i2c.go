@@ -0,0 +1,94 @@
+package ezo
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/reef-pi/rpi/i2c"
+)
+
+// Response codes returned as the first byte of every I2C read
+const (
+	codeSuccess     = 1
+	codeSyntaxError = 2
+	codePending     = 254
+	codeNoData      = 255
+)
+
+const (
+	_responseSize = 31
+	_maxPolls     = 20
+	_pollInterval = 100 * time.Millisecond
+)
+
+// sleep waits for the circuit to process a command
+var sleep = time.Sleep
+
+type i2cTransport struct {
+	mu    sync.Mutex
+	bus   i2c.Bus
+	addr  byte
+	delay time.Duration // interval between polls while the circuit is processing
+}
+
+// NewI2CTransport returns a transport for a circuit in I2C mode at addr
+func NewI2CTransport(bus i2c.Bus, addr byte) Transport {
+	return newI2CTransport(bus, addr)
+}
+
+func newI2CTransport(bus i2c.Bus, addr byte) *i2cTransport {
+	return &i2cTransport{
+		bus:   bus,
+		addr:  addr,
+		delay: _pollInterval,
+	}
+}
+
+func (t *i2cTransport) Send(cmd string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.write(cmd)
+}
+
+// Query sends cmd, waits for its processing time and returns the response
+// data, polling while the circuit is still processing.
+func (t *i2cTransport) Query(cmd string) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.write(cmd); err != nil {
+		return "", err
+	}
+	sleep(processingTime(cmd))
+	return t.read()
+}
+
+func (t *i2cTransport) write(cmd string) error {
+	return t.bus.WriteBytes(t.addr, []byte(cmd+"\000"))
+}
+
+func (t *i2cTransport) read() (string, error) {
+	for i := 0; i < _maxPolls; i++ {
+		payload, err := t.bus.ReadBytes(t.addr, _responseSize)
+		if err != nil {
+			return "", err
+		}
+		if len(payload) == 0 {
+			return "", fmt.Errorf("Empty response")
+		}
+		switch payload[0] {
+		case codeSuccess:
+			return strings.Trim(string(payload[1:]), "\000"), nil
+		case codePending:
+			sleep(t.delay)
+		case codeSyntaxError:
+			return "", ErrSyntax
+		case codeNoData:
+			return "", ErrNoData
+		default:
+			return "", fmt.Errorf("Failed to execute. Error:%s", string(payload))
+		}
+	}
+	return "", ErrPending
+}
@@ -0,0 +1,185 @@
+package ezo
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/reef-pi/hal"
+)
+
+/*
+https://files.atlas-scientific.com/EZO_PMP_Datasheet.pdf
+*/
+
+const _pumpName = "Atlas Scientific EZO(PMP)"
+
+// pumpDevice describes the EZO-PMP to the commands it shares with the sensor
+// circuits. Its reading is the volume dispensed.
+var pumpDevice = &device{
+	code:        "PMP",
+	description: "Atlas Scientific EZO peristaltic dosing pump",
+	channels:    []string{"volume"},
+}
+
+// Pump drives an EZO-PMP peristaltic pump. It is exposed as a single PWM
+// channel: Write switches continuous dispensing on and off, Set runs the
+// pump at a percentage of its maximum flow rate.
+type Pump struct {
+	ezo     *AtlasEZO
+	meta    hal.Metadata
+	mu      sync.Mutex
+	state   bool
+	maxRate float64
+}
+
+// Dispense pumps the given volume in ml. Negative volumes run the pump in reverse.
+func (p *Pump) Dispense(ml float64) error {
+	return p.run(fmt.Sprintf("D,%g", ml))
+}
+
+// DoseOverTime dispenses the given volume in ml evenly over minutes.
+func (p *Pump) DoseOverTime(ml, minutes float64) error {
+	return p.run(fmt.Sprintf("D,%g,%g", ml, minutes))
+}
+
+// ConstantFlow dispenses at the given rate in ml/min for minutes.
+func (p *Pump) ConstantFlow(rate, minutes float64) error {
+	return p.run(fmt.Sprintf("DC,%g,%g", rate, minutes))
+}
+
+// Pause pauses the current dispense. Calling it again resumes dispensing.
+func (p *Pump) Pause() error {
+	return p.ezo.command("P")
+}
+
+// Stop stops dispensing.
+func (p *Pump) Stop() error {
+	if err := p.ezo.command("X"); err != nil {
+		return err
+	}
+	p.setState(false)
+	return nil
+}
+
+// TotalVolume returns the volume in ml dispensed since power up or ClearTotalVolume.
+func (p *Pump) TotalVolume() (float64, error) {
+	return p.ezo.queryFloat("TV,?")
+}
+
+func (p *Pump) ClearTotalVolume() error {
+	return p.ezo.command("Clear")
+}
+
+// MaxFlowRate returns the maximum flow rate of the pump in ml/min.
+func (p *Pump) MaxFlowRate() (float64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.maxRate > 0 {
+		return p.maxRate, nil
+	}
+	r, err := p.ezo.queryFloat("DC,?")
+	if err != nil {
+		return 0, err
+	}
+	p.maxRate = r
+	return r, nil
+}
+
+// CalibrateVolume calibrates the pump with the volume in ml that was actually
+// dispensed by the preceding Dispense call.
+func (p *Pump) CalibrateVolume(ml float64) error {
+	return p.ezo.command(fmt.Sprintf("Cal,%g", ml))
+}
+
+func (p *Pump) ClearCalibration() error {
+	return p.ezo.ClearCalibration()
+}
+
+func (p *Pump) run(cmd string) error {
+	if err := p.ezo.command(cmd); err != nil {
+		return err
+	}
+	p.setState(true)
+	return nil
+}
+
+func (p *Pump) setState(s bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.state = s
+}
+
+func (p *Pump) Write(b bool) error {
+	if !b {
+		return p.Stop()
+	}
+	return p.run("D,*")
+}
+
+func (p *Pump) LastState() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.state
+}
+
+// Set runs the pump continuously at v percent of its maximum flow rate.
+func (p *Pump) Set(v float64) error {
+	if v <= 0 {
+		return p.Stop()
+	}
+	if v > 100 {
+		v = 100
+	}
+	max, err := p.MaxFlowRate()
+	if err != nil {
+		return err
+	}
+	return p.run(fmt.Sprintf("DC,%.2f,*", max*v/100))
+}
+
+func (p *Pump) Name() string {
+	return _pumpName
+}
+
+func (p *Pump) Number() int {
+	return 0
+}
+
+func (p *Pump) Close() error {
+	return nil
+}
+
+func (p *Pump) Metadata() hal.Metadata {
+	return p.meta
+}
+
+func (p *Pump) Pins(cap hal.Capability) ([]hal.Pin, error) {
+	switch cap {
+	case hal.DigitalOutput, hal.PWM:
+		return []hal.Pin{p}, nil
+	default:
+		return nil, fmt.Errorf("unsupported capability:%s", cap.String())
+	}
+}
+
+func (p *Pump) DigitalOutputPins() []hal.DigitalOutputPin {
+	return []hal.DigitalOutputPin{p}
+}
+
+func (p *Pump) DigitalOutputPin(n int) (hal.DigitalOutputPin, error) {
+	if n != 0 {
+		return nil, fmt.Errorf("EZO pump driver has only one valid channel: 0. Asked:%d", n)
+	}
+	return p, nil
+}
+
+func (p *Pump) PWMChannels() []hal.PWMChannel {
+	return []hal.PWMChannel{p}
+}
+
+func (p *Pump) PWMChannel(n int) (hal.PWMChannel, error) {
+	if n != 0 {
+		return nil, fmt.Errorf("EZO pump driver has only one valid channel: 0. Asked:%d", n)
+	}
+	return p, nil
+}
@@ -0,0 +1,58 @@
+package ezo
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/reef-pi/hal"
+)
+
+type pumpFactory struct {
+	factory
+}
+
+var ezoPumpFactory *pumpFactory
+var pumpOnce sync.Once
+
+// PumpFactory returns a singleton EZO-PMP Driver factory
+func PumpFactory() hal.DriverFactory {
+
+	pumpOnce.Do(func() {
+		ezoPumpFactory = &pumpFactory{
+			factory: factory{
+				meta: hal.Metadata{
+					Name:         _pumpName,
+					Description:  pumpDevice.description,
+					Capabilities: []hal.Capability{hal.DigitalOutput, hal.PWM},
+				},
+				parameters: []hal.ConfigParameter{
+					{
+						Name:    addressParam,
+						Type:    hal.Integer,
+						Order:   0,
+						Default: 103,
+					},
+				},
+			},
+		}
+	})
+
+	return ezoPumpFactory
+}
+
+func (f *pumpFactory) NewDriver(parameters map[string]interface{}, hardwareResources interface{}) (hal.Driver, error) {
+	if valid, failures := f.ValidateParameters(parameters); !valid {
+		return nil, errors.New(hal.ToErrorString(failures))
+	}
+
+	address, _ := hal.ConvertToInt(parameters[addressParam])
+	t, err := transportFor(address, hardwareResources)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Pump{
+		ezo:  &AtlasEZO{transport: t, spec: pumpDevice},
+		meta: f.meta,
+	}, nil
+}
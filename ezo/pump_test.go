@@ -0,0 +1,80 @@
+package ezo
+
+import (
+	"testing"
+
+	"github.com/reef-pi/hal"
+)
+
+func TestEZOPump(t *testing.T) {
+	bus := newFakeBus(map[string]string{
+		"TV,?": "?TV,12.50",
+		"DC,?": "?MaxRate,105.5",
+		"R":    "12.50",
+	})
+	d, err := PumpFactory().NewDriver(map[string]interface{}{"Address": 103}, bus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, ok := d.(*Pump)
+	if !ok {
+		t.Fatal("failed to type cast driver to Pump")
+	}
+	if !d.Metadata().HasCapability(hal.PWM) {
+		t.Error("PWM capability should exist")
+	}
+	if err := p.Dispense(5); err != nil {
+		t.Error(err)
+	}
+	if err := p.DoseOverTime(10, 60); err != nil {
+		t.Error(err)
+	}
+	if err := p.Pause(); err != nil {
+		t.Error(err)
+	}
+	v, err := p.TotalVolume()
+	if err != nil {
+		t.Error(err)
+	}
+	if v != 12.5 {
+		t.Error("expected total volume 12.5, found:", v)
+	}
+
+	ch, err := d.(hal.PWMDriver).PWMChannel(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ch.Set(50); err != nil {
+		t.Error(err)
+	}
+	if !ch.LastState() {
+		t.Error("expected pump to be running")
+	}
+	if err := ch.Write(false); err != nil {
+		t.Error(err)
+	}
+	if ch.LastState() {
+		t.Error("expected pump to be stopped")
+	}
+	if err := ch.Write(true); err != nil {
+		t.Error(err)
+	}
+	expected := []string{"D,5", "D,10,60", "P", "TV,?", "DC,?", "DC,52.75,*", "X", "D,*"}
+	if len(bus.commands) != len(expected) {
+		t.Fatal("expected commands", expected, "found:", bus.commands)
+	}
+	for i, c := range expected {
+		if bus.commands[i] != c {
+			t.Error("expected command", c, "found:", bus.commands[i])
+		}
+	}
+	if _, err := d.(hal.PWMDriver).PWMChannel(1); err == nil {
+		t.Error("expected error for invalid channel")
+	}
+	if p.ezo.Name() != _pumpName {
+		t.Error("unexpected circuit name:", p.ezo.Name())
+	}
+	if v, err := p.ezo.Value(); err != nil || v != 12.5 {
+		t.Error("expected dispensed volume 12.5, found:", v, err)
+	}
+}
@@ -0,0 +1,150 @@
+package ezo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+In UART mode commands and responses are terminated by a carriage return.
+With response codes enabled (the factory default) every command is answered
+by *OK or *ER, preceded by its data if any. Commands after which the circuit
+sleeps or reboots are answered too, by *OK followed by *SL or *RS. Circuits
+start in continuous mode, printing a reading every second. It is disabled
+before the first command, and readings that still arrive in between are
+skipped.
+*/
+
+// _responseTimeout is how long a circuit may stay silent after the processing
+// time of a command before it is considered unreachable
+const _responseTimeout = 2 * time.Second
+
+type serialTransport struct {
+	mu          sync.Mutex
+	w           io.Writer
+	lines       chan string
+	err         error // why lines was closed
+	timeout     time.Duration
+	initialized bool
+}
+
+// NewSerialTransport returns a transport for a circuit in UART mode. rw is
+// typically a serial port opened at the circuit's baud rate.
+func NewSerialTransport(rw io.ReadWriter) Transport {
+	t := &serialTransport{
+		w:       rw,
+		lines:   make(chan string, 16),
+		timeout: _responseTimeout,
+	}
+	go t.readLines(bufio.NewReader(rw))
+	return t
+}
+
+// readLines forwards every line received from the circuit until the reader
+// fails, so that responses can be awaited with a timeout.
+func (t *serialTransport) readLines(r *bufio.Reader) {
+	defer close(t.lines)
+	for {
+		line, err := r.ReadString('\r')
+		if err != nil {
+			t.err = err
+			return
+		}
+		t.lines <- strings.TrimSpace(line)
+	}
+}
+
+// Send sends cmd and waits for it to be acknowledged, so that the *OK is not
+// taken for the response of the next command.
+func (t *serialTransport) Send(cmd string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.init(); err != nil {
+		return err
+	}
+	_, err := t.query(cmd)
+	return err
+}
+
+func (t *serialTransport) Query(cmd string) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.init(); err != nil {
+		return "", err
+	}
+	return t.query(cmd)
+}
+
+// init disables continuous mode before the first command
+func (t *serialTransport) init() error {
+	if t.initialized {
+		return nil
+	}
+	if _, err := t.query("C,0"); err != nil {
+		return fmt.Errorf("failed to disable continuous mode: %w", err)
+	}
+	t.initialized = true
+	return nil
+}
+
+// query returns the last data line before *OK. Earlier data lines are
+// continuous mode readings sent before the command was processed.
+func (t *serialTransport) query(cmd string) (string, error) {
+	t.discard()
+	if err := t.write(cmd); err != nil {
+		return "", err
+	}
+	return t.response(processingTime(cmd) + t.timeout)
+}
+
+// discard drops lines left over from earlier commands, such as status
+// messages and continuous mode readings
+func (t *serialTransport) discard() {
+	for {
+		select {
+		case _, ok := <-t.lines:
+			if !ok {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+// response reads up to *OK or *ER, skipping status messages
+func (t *serialTransport) response(timeout time.Duration) (string, error) {
+	deadline := time.After(timeout)
+	var data string
+	for {
+		var line string
+		select {
+		case l, ok := <-t.lines:
+			if !ok {
+				return "", t.err
+			}
+			line = l
+		case <-deadline:
+			return "", ErrTimeout
+		}
+		switch {
+		case line == "*OK":
+			return data, nil
+		case line == "*ER":
+			return "", ErrSyntax
+		case strings.HasPrefix(line, "*"):
+			// *RS, *RE, *WA, *SL, *OV and *UV are status messages
+		case line != "":
+			data = line
+		}
+	}
+}
+
+func (t *serialTransport) write(cmd string) error {
+	_, err := io.WriteString(t.w, cmd+"\r")
+	return err
+}
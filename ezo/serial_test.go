@@ -0,0 +1,146 @@
+package ezo
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+// uartCircuit answers commands like a pH circuit in UART mode, still
+// printing continuous readings until it receives C,0. Responses are queued so
+// the circuit keeps reading commands when the client does not read them.
+func uartCircuit(conn net.Conn, commands chan<- string) {
+	out := make(chan string, 16)
+	go func() {
+		defer conn.Close()
+		for resp := range out {
+			if _, err := conn.Write([]byte(resp)); err != nil {
+				return
+			}
+		}
+	}()
+	defer close(out)
+	r := bufio.NewReader(conn)
+	continuous, asleep := true, false
+	for {
+		cmd, err := r.ReadString('\r')
+		if err != nil {
+			close(commands)
+			return
+		}
+		cmd = strings.TrimSuffix(cmd, "\r")
+		commands <- cmd
+		var resp string
+		if asleep {
+			resp = "*WA\r"
+			asleep = false
+		}
+		if continuous {
+			resp += "6.99\r"
+		}
+		switch cmd {
+		case "C,0":
+			continuous = false
+			resp += "*OK\r"
+		case "i":
+			resp += "?I,pH,2.16\r*OK\r"
+		case "R":
+			resp += "7.01\r*OK\r"
+		case "Sleep":
+			asleep = true
+			resp += "*OK\r*SL\r"
+		default:
+			if strings.HasPrefix(cmd, "L,") {
+				resp += "*OK\r"
+			} else {
+				resp += "*ER\r"
+			}
+		}
+		out <- resp
+	}
+}
+
+func TestEZOSerialTransport(t *testing.T) {
+	client, circuit := net.Pipe()
+	commands := make(chan string, 16)
+	go uartCircuit(circuit, commands)
+
+	a, err := New(NewSerialTransport(client), autoDetect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Name() != _ezoName {
+		t.Error("unexpected name:", a.Name())
+	}
+	v, err := a.Value()
+	if err != nil {
+		t.Error(err)
+	}
+	if v != 7.01 {
+		t.Error("expected 7.01, found:", v)
+	}
+	if err := a.LedOn(); err != nil {
+		t.Error(err)
+	}
+	if err := a.command("Bogus"); !errors.Is(err, ErrSyntax) {
+		t.Error("expected syntax error, found:", err)
+	}
+	if err := a.Sleep(); err != nil {
+		t.Error(err)
+	}
+	// the acknowledgement of Sleep must not be taken for the reading
+	if v, err := a.Value(); err != nil || v != 7.01 {
+		t.Error("expected 7.01 after waking up, found:", v, err)
+	}
+	client.Close()
+
+	var sent []string
+	for c := range commands {
+		sent = append(sent, c)
+	}
+	expected := []string{"C,0", "i", "R", "L,1", "Bogus", "Sleep", "R"}
+	if strings.Join(sent, " ") != strings.Join(expected, " ") {
+		t.Error("expected commands", expected, "found:", sent)
+	}
+}
+
+func TestEZOSerialSendDisablesContinuousMode(t *testing.T) {
+	client, circuit := net.Pipe()
+	commands := make(chan string, 16)
+	go uartCircuit(circuit, commands)
+
+	a, err := New(NewSerialTransport(client), "pH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Sleep(); err != nil {
+		t.Error(err)
+	}
+	client.Close()
+	var sent []string
+	for c := range commands {
+		sent = append(sent, c)
+	}
+	if strings.Join(sent, " ") != "C,0 Sleep" {
+		t.Error("expected continuous mode to be disabled before Sleep, found:", sent)
+	}
+}
+
+func TestEZOSerialTimeout(t *testing.T) {
+	client, circuit := net.Pipe()
+	defer client.Close()
+	// a silent circuit reads commands and never answers
+	go io.Copy(io.Discard, circuit)
+
+	tr := NewSerialTransport(client)
+	tr.(*serialTransport).timeout = 0
+	if _, err := tr.Query("L,?"); !errors.Is(err, ErrTimeout) {
+		t.Error("expected timeout, found:", err)
+	}
+	if err := tr.Send("Sleep"); !errors.Is(err, ErrTimeout) {
+		t.Error("expected timeout, found:", err)
+	}
+}
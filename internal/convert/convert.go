@@ -0,0 +1,26 @@
+// Package convert holds helpers to read driver configuration parameters that
+// are not provided by hal.
+package convert
+
+import (
+	"strconv"
+
+	"github.com/reef-pi/hal"
+)
+
+// ToFloat converts a decimal parameter, which may have been decoded
+// as a float, an integer or a string.
+func ToFloat(v interface{}) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case float32:
+		return float64(t), true
+	case string:
+		f, err := strconv.ParseFloat(t, 64)
+		return f, err == nil
+	default:
+		i, ok := hal.ConvertToInt(v)
+		return float64(i), ok
+	}
+}
@@ -0,0 +1,17 @@
+package convert
+
+import "testing"
+
+func TestToFloat(t *testing.T) {
+	for _, v := range []interface{}{0.5, float32(0.5), "0.5"} {
+		if f, ok := ToFloat(v); !ok || f != 0.5 {
+			t.Error("expected 0.5 from", v, "found:", f, ok)
+		}
+	}
+	if f, ok := ToFloat(2); !ok || f != 2 {
+		t.Error("expected 2 from an integer, found:", f, ok)
+	}
+	if _, ok := ToFloat("half"); ok {
+		t.Error("expected non numeric string to fail")
+	}
+}
@@ -0,0 +1,225 @@
+package pca9685
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	curveParam         = "Curve"
+	curveTableParam    = "Curve Table"
+	channelCurvesParam = "Channel Curves"
+)
+
+// Curve maps a value within 0-100 to the duty cycle, also within 0-100,
+// output by a channel. Curves must map 0 to 0 and 100 to 100.
+type Curve func(float64) float64
+
+// Linear outputs the value as the duty cycle.
+func Linear(v float64) float64 { return v }
+
+// CIE1931 treats the value as perceived lightness and outputs the matching
+// luminance, following the CIE 1931 lightness formula.
+func CIE1931(v float64) float64 {
+	if v <= 8 {
+		return v / 903.3 * 100
+	}
+	return math.Pow((v+16)/116, 3) * 100
+}
+
+// Gamma returns a curve raising the value to the power g.
+func Gamma(g float64) Curve {
+	return func(v float64) float64 {
+		return math.Pow(v/100, g) * 100
+	}
+}
+
+// Table returns a curve interpolating between duty cycles sampled at evenly
+// spaced values, from 0 to 100.
+func Table(points []float64) (Curve, error) {
+	if len(points) < 2 {
+		return nil, fmt.Errorf("a curve table needs at least 2 points. %d were received", len(points))
+	}
+	for _, p := range points {
+		if p < 0 || p > 100 {
+			return nil, fmt.Errorf("curve table point %v is out of range (0 - 100)", p)
+		}
+	}
+	if points[0] != 0 || points[len(points)-1] != 100 {
+		return nil, fmt.Errorf("a curve table must start at 0 and end at 100")
+	}
+	pts := append([]float64(nil), points...)
+	return func(v float64) float64 {
+		x := v / 100 * float64(len(pts)-1)
+		i := int(x)
+		if i >= len(pts)-1 {
+			return pts[len(pts)-1]
+		}
+		return pts[i] + (pts[i+1]-pts[i])*(x-float64(i))
+	}, nil
+}
+
+// parseCurve parses a curve name: linear, cie1931, gamma followed by the
+// exponent (e.g. "gamma 2.2"), or table for the points of the curve table.
+func parseCurve(v interface{}, table interface{}) (Curve, error) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf(" is not a string. %v was received.", v)
+	}
+	fields := strings.Fields(strings.ToLower(s))
+	switch {
+	case len(fields) == 0, len(fields) == 1 && fields[0] == "linear":
+		return Linear, nil
+	case len(fields) == 1 && fields[0] == "cie1931":
+		return CIE1931, nil
+	case len(fields) == 2 && fields[0] == "gamma":
+		g, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil || g <= 0 {
+			return nil, fmt.Errorf(" has an invalid gamma %q.", fields[1])
+		}
+		return Gamma(g), nil
+	case len(fields) == 1 && fields[0] == "table":
+		strs, err := parseList(table)
+		if err != nil {
+			return nil, err
+		}
+		var points []float64
+		for _, p := range strs {
+			f, err := strconv.ParseFloat(p, 64)
+			if err != nil {
+				return nil, fmt.Errorf(" table has an invalid point %q.", p)
+			}
+			points = append(points, f)
+		}
+		c, err := Table(points)
+		if err != nil {
+			return nil, fmt.Errorf(" %s.", err.Error())
+		}
+		return c, nil
+	default:
+		return nil, fmt.Errorf(" is not a valid value of linear, cie1931, gamma <n> or table. %v was received.", v)
+	}
+}
+
+// parseChannelCurves parses channel specific curves, as semicolon separated
+// channel=curve pairs (e.g. "0=cie1931; 3=gamma 2.8"), for n channels.
+func parseChannelCurves(v interface{}, table interface{}, n int) (map[int]Curve, error) {
+	if v == nil {
+		return nil, nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf(" is not a string. %v was received.", v)
+	}
+	curves := make(map[int]Curve)
+	for _, pair := range strings.Split(s, ";") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf(" has an invalid channel curve %q.", pair)
+		}
+		chs, err := parseChannels(kv[0], n)
+		if err != nil {
+			return nil, err
+		}
+		c, err := parseCurve(kv[1], table)
+		if err != nil {
+			return nil, err
+		}
+		for _, ch := range chs {
+			curves[ch] = c
+		}
+	}
+	return curves, nil
+}
+
+func (f *pcaFactory) validateCurves(parameters map[string]interface{}, failures map[string][]string) {
+	if v, ok := parameters[curveParam]; ok {
+		if _, err := parseCurve(v, parameters[curveTableParam]); err != nil {
+			failures[curveParam] = append(failures[curveParam], fmt.Sprint(curveParam, err.Error()))
+		}
+	}
+	if v, ok := parameters[channelCurvesParam]; ok {
+		if _, err := parseChannelCurves(v, parameters[curveTableParam], chainLength(parameters)*numChannels); err != nil {
+			failures[channelCurvesParam] = append(failures[channelCurvesParam], fmt.Sprint(channelCurvesParam, err.Error()))
+		}
+	}
+}
+
+// fadeStep is the interval between updates of a fade
+var fadeStep = 10 * time.Millisecond
+
+// Fader is implemented by pca9685 PWM channels to change their output
+// gradually.
+type Fader interface {
+	// Fade ramps the channel from its current value to target, within 0-100,
+	// over d in the background. It replaces any fade in progress.
+	Fade(target float64, d time.Duration) error
+	// StopFade stops a fade in progress, leaving the channel at its
+	// current value.
+	StopFade()
+	// SetCurve changes the output curve of the channel.
+	SetCurve(Curve)
+}
+
+func (c *pca9685Channel) SetCurve(curve Curve) {
+	c.driver.mu.Lock()
+	defer c.driver.mu.Unlock()
+	c.curve = curve
+}
+
+func (c *pca9685Channel) Fade(target float64, d time.Duration) error {
+	if target < 0 || target > 100 {
+		return fmt.Errorf("invalid value: %f outside 0-100", target)
+	}
+	c.StopFade()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	from := c.v
+	steps := int(d / fadeStep)
+	if steps < 1 {
+		steps = 1
+	}
+	cancel, done := make(chan struct{}), make(chan struct{})
+	c.cancel, c.done = cancel, done
+	interval := fadeStep
+	go func() {
+		defer close(done)
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for i := 1; i <= steps; i++ {
+			select {
+			case <-cancel:
+				return
+			case <-t.C:
+			}
+			v := from + (target-from)*float64(i)/float64(steps)
+			if err := c.driver.set(c.channel, v); err != nil {
+				log.Println("pca9685: fade of channel", c.channel, "to", target, "stopped. Error:", err)
+				return
+			}
+			c.mu.Lock()
+			c.v = v
+			c.mu.Unlock()
+		}
+	}()
+	return nil
+}
+
+func (c *pca9685Channel) StopFade() {
+	c.mu.Lock()
+	cancel, done := c.cancel, c.done
+	c.cancel, c.done = nil, nil
+	c.mu.Unlock()
+	if cancel != nil {
+		close(cancel)
+		<-done
+	}
+}
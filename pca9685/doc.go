@@ -1,2 +1,2 @@
-// Package pca9685 defines an I2C driver for a single PCA9685 chip connected over I2C
+// Package pca9685 defines an I2C driver for one or more PCA9685 chips connected over I2C
 package pca9685
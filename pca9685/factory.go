@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"strconv"
 	"sync"
 
 	"github.com/reef-pi/hal"
@@ -12,6 +13,8 @@ import (
 
 const addressParam = "Address"
 const freqParam = "Frequency"
+const addressesParam = "Addresses"
+const allCallParam = "All Call Off"
 
 type pcaFactory struct {
 	meta       hal.Metadata
@@ -28,7 +31,7 @@ func Factory() hal.DriverFactory {
 		factory = &pcaFactory{
 			meta: hal.Metadata{
 				Name:        "pca9685",
-				Description: "Supports one PCA9685 chip",
+				Description: "Supports one or more chained PCA9685 chips",
 				Capabilities: []hal.Capability{
 					hal.PWM, hal.DigitalOutput,
 				},
@@ -46,6 +49,120 @@ func Factory() hal.DriverFactory {
 					Order:   1,
 					Default: 150,
 				},
+				{
+					Name:    addressesParam,
+					Type:    hal.String,
+					Order:   2,
+					Default: "",
+				},
+				{
+					Name:    allCallParam,
+					Type:    hal.Boolean,
+					Order:   3,
+					Default: false,
+				},
+				{
+					Name:    invertParam,
+					Type:    hal.Boolean,
+					Order:   4,
+					Default: false,
+				},
+				{
+					Name:    driveParam,
+					Type:    hal.String,
+					Order:   5,
+					Default: "totem-pole",
+				},
+				{
+					Name:    changeParam,
+					Type:    hal.String,
+					Order:   6,
+					Default: "stop",
+				},
+				{
+					Name:    disabledParam,
+					Type:    hal.String,
+					Order:   7,
+					Default: "low",
+				},
+				{
+					Name:    invertChannelParam,
+					Type:    hal.String,
+					Order:   8,
+					Default: "",
+				},
+				{
+					Name:    staggerParam,
+					Type:    hal.Boolean,
+					Order:   9,
+					Default: false,
+				},
+				{
+					Name:    offsetsParam,
+					Type:    hal.String,
+					Order:   10,
+					Default: "",
+				},
+				{
+					Name:    curveParam,
+					Type:    hal.String,
+					Order:   11,
+					Default: "linear",
+				},
+				{
+					Name:    curveTableParam,
+					Type:    hal.String,
+					Order:   12,
+					Default: "",
+				},
+				{
+					Name:    channelCurvesParam,
+					Type:    hal.String,
+					Order:   13,
+					Default: "",
+				},
+				{
+					Name:    servoChannelsParam,
+					Type:    hal.String,
+					Order:   14,
+					Default: "",
+				},
+				{
+					Name:    servoMinParam,
+					Type:    hal.Integer,
+					Order:   15,
+					Default: 1000,
+				},
+				{
+					Name:    servoCenterParam,
+					Type:    hal.Integer,
+					Order:   16,
+					Default: 1500,
+				},
+				{
+					Name:    servoMaxParam,
+					Type:    hal.Integer,
+					Order:   17,
+					Default: 2000,
+				},
+				{
+					Name:    servoRangeParam,
+					Type:    hal.Integer,
+					Order:   18,
+					Default: 180,
+				},
+				{
+					Name:    servoSpeedParam,
+					Type:    hal.Integer,
+					Order:   19,
+					Default: 0,
+				},
+				{
+					Name:    keepParam,
+					Type:    hal.Boolean,
+					Order:   20,
+					Default: false,
+				},
 			},
 		}
 	})
@@ -67,6 +184,25 @@ func (f *pcaFactory) ValidateParameters(parameters map[string]interface{}) (bool
 	var v interface{}
 	var ok bool
 
+	if v, ok = parameters[addressesParam]; ok {
+		if _, err := parseAddresses(v); err != nil {
+			failures[addressesParam] = append(failures[addressesParam], fmt.Sprint(addressesParam, err.Error()))
+		}
+	}
+
+	if v, ok = parameters[allCallParam]; ok {
+		if _, ok := v.(bool); !ok {
+			failure := fmt.Sprint(allCallParam, " is not a boolean. ", v, " was received.")
+			failures[allCallParam] = append(failures[allCallParam], failure)
+		}
+	}
+
+	f.validateOutput(parameters, failures)
+	f.validatePhase(parameters, failures)
+	f.validateCurves(parameters, failures)
+	f.validateServo(parameters, failures)
+	f.validateKeep(parameters, failures)
+
 	if v, ok = parameters[addressParam]; ok {
 		val, ok := hal.ConvertToInt(v)
 		if !ok {
@@ -77,7 +213,7 @@ func (f *pcaFactory) ValidateParameters(parameters map[string]interface{}) (bool
 			failure := fmt.Sprint(addressParam, " is out of range (1 - 255). ", v, " was received.")
 			failures[addressParam] = append(failures[addressParam], failure)
 		}
-	} else {
+	} else if addrs, _ := parseAddresses(parameters[addressesParam]); len(addrs) == 0 {
 		failure := fmt.Sprint(addressParam, " is required parameter, but was not received.")
 		failures[addressParam] = append(failures[addressParam], failure)
 	}
@@ -107,32 +243,37 @@ func (f *pcaFactory) NewDriver(parameters map[string]interface{}, hardwareResour
 
 	address, _ := hal.ConvertToInt(parameters[addressParam])
 	frequency, _ := hal.ConvertToInt(parameters[freqParam])
+	allCall, _ := parameters[allCallParam].(bool)
+	keep, _ := parameters[keepParam].(bool)
 
-	config := PCA9685Config{
-		Address:   address,
-		Frequency: frequency,
+	addresses, _ := parseAddresses(parameters[addressesParam])
+	if len(addresses) == 0 {
+		addresses = []int{address}
 	}
 
 	bus := hardwareResources.(i2c.Bus)
 
-	hwDriver := &PCA9685{
-		addr: byte(address),
-		bus:  bus,
-		Freq: frequency,
+	if frequency == 0 {
+		log.Println("WARNING: pca9685 driver pwm frequency set to 0. Falling back to 1500")
+		frequency = 1500
 	}
 
 	pwm := pca9685Driver{
-		mu:       &sync.Mutex{},
-		hwDriver: hwDriver,
+		mu:      &sync.Mutex{},
+		bus:     bus,
+		allCall: allCall,
+		keep:    keep,
 	}
-	if config.Frequency == 0 {
-		log.Println("WARNING: pca9685 driver pwm frequency set to 0. Falling back to 1500")
-		config.Frequency = 1500
+
+	for _, addr := range addresses {
+		hwDriver := New(byte(addr), bus)
+		hwDriver.Freq = frequency
+		hwDriver.Mode2 = mode2(parameters)
+		pwm.chips = append(pwm.chips, hwDriver)
 	}
-	hwDriver.Freq = config.Frequency // overriding default
 
-	// Create the 16 channels the hardware has
-	for i := 0; i < 16; i++ {
+	// Create the 16 channels each chip has
+	for i := 0; i < 16*len(pwm.chips); i++ {
 		ch := &pca9685Channel{
 			channel: i,
 			driver:  &pwm,
@@ -140,6 +281,73 @@ func (f *pcaFactory) NewDriver(parameters map[string]interface{}, hardwareResour
 		pwm.channels = append(pwm.channels, ch)
 	}
 
-	// Wake the hardware
-	return &pwm, hwDriver.Wake()
+	inverted, _ := parseChannels(parameters[invertChannelParam], len(pwm.channels))
+	for _, ch := range inverted {
+		pwm.channels[ch].inverted = true
+	}
+	for i, offset := range phaseOffsets(parameters, len(pwm.channels)) {
+		pwm.channels[i].phase = offset
+	}
+	curve, _ := parseCurve(parameters[curveParam], parameters[curveTableParam])
+	curves, _ := parseChannelCurves(parameters[channelCurvesParam], parameters[curveTableParam], len(pwm.channels))
+	for i, ch := range pwm.channels {
+		ch.curve = curve
+		if c, ok := curves[i]; ok {
+			ch.curve = c
+		}
+	}
+	servos, _ := parseChannels(parameters[servoChannelsParam], len(pwm.channels))
+	for _, ch := range servos {
+		if pwm.servos == nil {
+			pwm.servos = make(map[int]*Servo)
+		}
+		pwm.servos[ch] = &Servo{
+			driver:  &pwm,
+			channel: ch,
+			cfg:     servoConfig(parameters),
+		}
+	}
+
+	// Wake the hardware, leaving running chips untouched when outputs are kept
+	for _, hwDriver := range pwm.chips {
+		if keep {
+			resumed, err := hwDriver.Resume()
+			if err != nil {
+				return &pwm, err
+			}
+			if resumed {
+				continue
+			}
+		}
+		if err := hwDriver.Wake(); err != nil {
+			return &pwm, err
+		}
+	}
+	return &pwm, pwm.restore()
+}
+
+// parseAddresses parses a comma or space separated list of chip addresses,
+// in decimal or 0x prefixed hexadecimal. An absent or empty list is valid.
+func parseAddresses(v interface{}) ([]int, error) {
+	fields, err := parseList(v)
+	if err != nil {
+		return nil, err
+	}
+	var addrs []int
+	seen := make(map[int]bool)
+	for _, f := range fields {
+		a, err := strconv.ParseInt(f, 0, 0)
+		if err != nil {
+			return nil, fmt.Errorf(" has an invalid address %q.", f)
+		}
+		if a <= 0 || a >= 256 {
+			return nil, fmt.Errorf(" address is out of range (1 - 255). %v was received.", f)
+		}
+		if seen[int(a)] {
+			return nil, fmt.Errorf(" lists address %v more than once.", f)
+		}
+		seen[int(a)] = true
+		addrs = append(addrs, int(a))
+	}
+	return addrs, nil
 }
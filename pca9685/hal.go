@@ -2,10 +2,12 @@ package pca9685
 
 import (
 	"fmt"
+	"math"
 	"sort"
 	"sync"
 
 	"github.com/reef-pi/hal"
+	"github.com/reef-pi/rpi/i2c"
 )
 
 type PCA9685Config struct {
@@ -14,19 +16,29 @@ type PCA9685Config struct {
 }
 
 type pca9685Channel struct {
-	driver  *pca9685Driver
-	channel int
-	v       float64
+	driver   *pca9685Driver
+	channel  int
+	inverted bool
+	phase    uint16
+	curve    Curve
+	mu       sync.Mutex
+	v        float64
+	cancel   chan struct{}
+	done     chan struct{}
 }
 
 func (c *pca9685Channel) Name() string { return fmt.Sprintf("%d", c.channel) }
 func (c *pca9685Channel) Number() int  { return c.channel }
-func (c *pca9685Channel) Close() error { return nil }
+func (c *pca9685Channel) Close() error {
+	c.StopFade()
+	return nil
+}
 func (c *pca9685Channel) Set(value float64) error {
+	c.StopFade()
 	if err := c.driver.set(c.channel, value); err != nil {
 		return err
 	}
-	c.v = value
+	c.record(value)
 	return nil
 }
 func (c *pca9685Channel) Write(b bool) error {
@@ -34,34 +46,81 @@ func (c *pca9685Channel) Write(b bool) error {
 	if b {
 		v = 100
 	}
-	if err := c.driver.set(c.channel, v); err != nil {
-		return err
-	}
-	c.v = v
-	return nil
+	return c.Set(v)
 }
 
-func (c *pca9685Channel) LastState() bool { return c.v == 100 }
+func (c *pca9685Channel) LastState() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.v == 100
+}
+
+func (c *pca9685Channel) record(v float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.v = v
+}
 
 type pca9685Driver struct {
-	hwDriver *PCA9685
+	chips    []*PCA9685
+	bus      i2c.Bus
+	allCall  bool
+	keep     bool
 	mu       *sync.Mutex
 	channels []*pca9685Channel
+	servos   map[int]*Servo
+}
+
+// pwmOutput is implemented by both PWM and servo channels
+type pwmOutput interface {
+	hal.PWMChannel
+	hal.DigitalOutputPin
+}
+
+// output returns the servo or the PWM channel numbered n
+func (p *pca9685Driver) output(n int) pwmOutput {
+	if s, ok := p.servos[n]; ok {
+		return s
+	}
+	return p.channels[n]
 }
 
 func (p *pca9685Driver) Close() error {
-	// Close the driver (will clear all registers)
-	if err := p.hwDriver.Close(); err != nil {
-		return err
+	for _, s := range p.servos {
+		s.Close()
 	}
-	// Send the hardware to sleep
-	return p.hwDriver.Sleep()
+	for _, ch := range p.channels {
+		ch.Close()
+	}
+	// Leave the outputs running for the next start
+	if p.keep {
+		return nil
+	}
+	// Turn every output of every chip off in a single write
+	if p.allCall {
+		if err := allCallOff(p.bus); err != nil {
+			return err
+		}
+	}
+	for _, hwDriver := range p.chips {
+		// Close the driver (will clear all registers)
+		if !p.allCall {
+			if err := hwDriver.Close(); err != nil {
+				return err
+			}
+		}
+		// Send the hardware to sleep
+		if err := hwDriver.Sleep(); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (p *pca9685Driver) Metadata() hal.Metadata {
 	return hal.Metadata{
 		Name:        "pca9685",
-		Description: "Supports one PCA9685 chip",
+		Description: "Supports one or more chained PCA9685 chips",
 		Capabilities: []hal.Capability{
 			hal.PWM, hal.DigitalOutput,
 		},
@@ -71,8 +130,8 @@ func (p *pca9685Driver) Metadata() hal.Metadata {
 func (p *pca9685Driver) PWMChannels() []hal.PWMChannel {
 	// Return array of channels soreted by name
 	var chs []hal.PWMChannel
-	for _, ch := range p.channels {
-		chs = append(chs, ch)
+	for i := range p.channels {
+		chs = append(chs, p.output(i))
 	}
 	sort.Slice(chs, func(i, j int) bool { return chs[i].Name() < chs[j].Name() })
 	return chs
@@ -83,12 +142,12 @@ func (p *pca9685Driver) PWMChannel(chnum int) (hal.PWMChannel, error) {
 	if chnum < 0 || chnum >= len(p.channels) {
 		return nil, fmt.Errorf("invalid channel %d", chnum)
 	}
-	return p.channels[chnum], nil
+	return p.output(chnum), nil
 }
 func (p *pca9685Driver) DigitalOutputPins() []hal.DigitalOutputPin {
 	pins := make([]hal.DigitalOutputPin, len(p.channels))
-	for i, ch := range p.channels {
-		pins[i] = ch
+	for i := range p.channels {
+		pins[i] = p.output(i)
 	}
 	return pins
 }
@@ -97,22 +156,120 @@ func (p *pca9685Driver) DigitalOutputPin(n int) (hal.DigitalOutputPin, error) {
 	return p.PWMChannel(n)
 }
 
-// value should be within 0-100
+// BatchSetter is implemented by the pca9685 driver to update many channels
+// at once. Values are within 0-100 and keyed by channel number.
+type BatchSetter interface {
+	SetBatch(values map[int]float64) error
+}
+
+// value should be within 0-100. Pins are numbered across the chain, 16 per chip.
 func (p *pca9685Driver) set(pin int, value float64) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
+	pwm, err := p.counts(pin, value)
+	if err != nil {
+		return err
+	}
+	return p.chips[pin/numChannels].SetPwm(pin%numChannels, pwm.On, pwm.Off)
+}
+
+// write outputs raw counts on a pin, moved by the phase offset of the pin
+func (p *pca9685Driver) write(pin int, pwm PWM) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pwm = shift(pwm, p.channels[pin].phase)
+	return p.chips[pin/numChannels].SetPwm(pin%numChannels, pwm.On, pwm.Off)
+}
+
+// SetBatch updates the given channels with one burst write per chip, so they
+// all change in the same PWM period. Channels between the updated ones are
+// rewritten with their current value.
+func (p *pca9685Driver) SetBatch(values map[int]float64) error {
+	for pin := range values {
+		if pin >= 0 && pin < len(p.channels) {
+			p.channels[pin].StopFade()
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	updates := make([]map[int]PWM, len(p.chips))
+	for pin, value := range values {
+		if pin < 0 || pin >= len(p.channels) {
+			return fmt.Errorf("invalid channel %d", pin)
+		}
+		if _, ok := p.servos[pin]; ok {
+			return fmt.Errorf("channel %d is a servo", pin)
+		}
+		pwm, err := p.counts(pin, value)
+		if err != nil {
+			return err
+		}
+		chip := pin / numChannels
+		if updates[chip] == nil {
+			updates[chip] = make(map[int]PWM)
+		}
+		updates[chip][pin%numChannels] = pwm
+	}
+
+	for chip, update := range updates {
+		if len(update) == 0 {
+			continue
+		}
+		hwDriver := p.chips[chip]
+		first, last := numChannels, -1
+		for ch := range update {
+			first, last = min(first, ch), max(last, ch)
+		}
+		pwms := append([]PWM(nil), hwDriver.leds[first:last+1]...)
+		for ch, pwm := range update {
+			pwms[ch-first] = pwm
+		}
+		if err := hwDriver.SetPwms(first, pwms); err != nil {
+			return err
+		}
+		for ch := range update {
+			p.channels[chip*numChannels+ch].record(values[chip*numChannels+ch])
+		}
+	}
+	return nil
+}
+
+// counts converts a value within 0-100 to channel on and off counts. The
+// value goes through the output curve of the channel, then inverted channels
+// output 100-value, so that 100 still means fully on for the load.
+// The on-time is moved by the phase offset of the channel.
+func (p *pca9685Driver) counts(pin int, value float64) (PWM, error) {
+	pwm, err := p.duty(pin, value)
+	if err != nil {
+		return pwm, err
+	}
+	return shift(pwm, p.channels[pin].phase), nil
+}
+
+func (p *pca9685Driver) duty(pin int, value float64) (PWM, error) {
 	switch {
 	case value > 100:
-		return fmt.Errorf("invalid value: %f above 100", value)
+		return PWM{}, fmt.Errorf("invalid value: %f above 100", value)
 	case value < 0:
-		return fmt.Errorf("invalid value: %f below 0", value)
+		return PWM{}, fmt.Errorf("invalid value: %f below 0", value)
+	}
+	if curve := p.channels[pin].curve; curve != nil {
+		value = math.Max(0, math.Min(100, curve(value)))
+	}
+	if p.channels[pin].inverted {
+		value = 100 - value
+	}
+	switch {
 	case value == 0:
-		return p.hwDriver.SetPwm(pin, 0, 4096)
+		return PWM{On: 0, Off: 4096}, nil
 	case value == 100:
-		return p.hwDriver.SetPwm(pin, 4096, 0)
+		return PWM{On: 4096, Off: 0}, nil
 	default:
-		return p.hwDriver.SetPwm(pin, 0, uint16(value*40.95))
+		return PWM{On: 0, Off: uint16(value * 40.95)}, nil
 	}
 }
 
@@ -120,8 +277,8 @@ func (p *pca9685Driver) Pins(cap hal.Capability) ([]hal.Pin, error) {
 	switch cap {
 	case hal.DigitalOutput, hal.PWM:
 		var pins []hal.Pin
-		for _, pin := range p.channels {
-			pins = append(pins, pin)
+		for i := range p.channels {
+			pins = append(pins, p.output(i))
 		}
 		return pins, nil
 	default:
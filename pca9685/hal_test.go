@@ -1,7 +1,9 @@
 package pca9685
 
 import (
+	"math"
 	"testing"
+	"time"
 
 	"github.com/reef-pi/hal"
 	"github.com/reef-pi/rpi/i2c"
@@ -95,3 +97,641 @@ func TestPca9685Driver_Close(t *testing.T) {
 		t.Errorf("unexpected error closing driver %v", err)
 	}
 }
+
+// fakeBus records the register map of every chip on the bus, applying
+// multi-byte writes to consecutive registers. Chips start in their power on
+// state.
+type fakeBus struct {
+	regs   map[byte]*[256]byte
+	writes int
+	mode1  []byte
+}
+
+func newFakeBus() *fakeBus { return &fakeBus{regs: make(map[byte]*[256]byte)} }
+
+func (b *fakeBus) chip(addr byte) *[256]byte {
+	if _, ok := b.regs[addr]; !ok {
+		regs := new([256]byte)
+		regs[mode1RegAddr], regs[mode2RegAddr], regs[preScaleRegAddr] = 0x11, 0x04, 0x1E
+		for ch := 0; ch < numChannels; ch++ {
+			regs[pwm0OnLowReg+4*ch+3] = 0x10
+		}
+		b.regs[addr] = regs
+	}
+	return b.regs[addr]
+}
+
+func (b *fakeBus) SetAddress(_ byte) error                 { return nil }
+func (b *fakeBus) ReadBytes(_ byte, n int) ([]byte, error) { return make([]byte, n), nil }
+func (b *fakeBus) WriteBytes(_ byte, _ []byte) error       { return nil }
+func (b *fakeBus) Close() error                            { return nil }
+func (b *fakeBus) ReadFromReg(addr, reg byte, v []byte) error {
+	copy(v, b.chip(addr)[reg:])
+	return nil
+}
+func (b *fakeBus) WriteToReg(addr, reg byte, v []byte) error {
+	b.writes++
+	if reg == mode1RegAddr {
+		b.mode1 = append(b.mode1, v[0])
+	}
+	copy(b.chip(addr)[reg:], v)
+	return nil
+}
+
+// led returns the on and off counts of a channel
+func (b *fakeBus) led(addr byte, ch int) (uint16, uint16) {
+	r := b.chip(addr)[pwm0OnLowReg+4*ch:]
+	return uint16(r[0]) | uint16(r[1])<<8, uint16(r[2]) | uint16(r[3])<<8
+}
+
+func TestChainedChips(t *testing.T) {
+	bus := newFakeBus()
+	p := map[string]interface{}{
+		"Frequency":    200,
+		"Addresses":    "0x40, 0x41,66",
+		"All Call Off": true,
+	}
+	f := Factory()
+	if valid, failures := f.ValidateParameters(p); !valid {
+		t.Fatal(failures)
+	}
+	d, err := f.NewDriver(p, bus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pwm := d.(hal.PWMDriver)
+	if l := len(pwm.PWMChannels()); l != 48 {
+		t.Fatalf("expected 48 channels, got %d", l)
+	}
+	for _, addr := range []byte{0x40, 0x41, 0x42} {
+		if pre := bus.chip(addr)[preScaleRegAddr]; pre != 29 {
+			t.Errorf("chip %#x: expected prescale 29, got %d", addr, pre)
+		}
+	}
+
+	ch, err := pwm.PWMChannel(37)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ch.Set(100); err != nil {
+		t.Fatal(err)
+	}
+	if on, _ := bus.led(0x42, 5); on != 4096 {
+		t.Error("channel 37 should map to channel 5 of the third chip")
+	}
+	if _, err := pwm.PWMChannel(48); err == nil {
+		t.Error("channel 48 should not exist")
+	}
+
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	all := bus.chip(allCallAddr)[allLedOnLowReg:]
+	if all[3] != 0x10 {
+		t.Error("expected ALL_LED_OFF full off through the ALL_CALL address")
+	}
+}
+
+func TestAddressesValidation(t *testing.T) {
+	f := Factory()
+	for _, addrs := range []interface{}{"0x40,0x40", "0x40,foo", "0x40,300", 64} {
+		if valid, _ := f.ValidateParameters(map[string]interface{}{"Frequency": 200, "Addresses": addrs}); valid {
+			t.Errorf("addresses %v should be rejected", addrs)
+		}
+	}
+	if valid, _ := f.ValidateParameters(map[string]interface{}{"Frequency": 200, "Addresses": ""}); valid {
+		t.Error("an address is required")
+	}
+}
+
+func TestBurstWrites(t *testing.T) {
+	bus := newFakeBus()
+	d, err := Factory().NewDriver(map[string]interface{}{"Frequency": 200, "Addresses": "0x40,0x41"}, bus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode1 := bus.chip(0x40)[mode1RegAddr]; mode1&mode1AutoIncrement == 0 {
+		t.Errorf("expected auto-increment in MODE1, got %#x", mode1)
+	}
+	pwm := d.(hal.PWMDriver)
+	ch, _ := pwm.PWMChannel(3)
+	bus.writes = 0
+	if err := ch.Set(50); err != nil {
+		t.Fatal(err)
+	}
+	if bus.writes != 1 {
+		t.Errorf("expected a single write per channel, got %d", bus.writes)
+	}
+	if _, off := bus.led(0x40, 3); off != 2047 {
+		t.Error("expected off count 2047, got", off)
+	}
+
+	batch := d.(BatchSetter)
+	bus.writes = 0
+	if err := batch.SetBatch(map[int]float64{1: 100, 5: 25, 20: 0, 31: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if bus.writes != 2 {
+		t.Errorf("expected one write per chip, got %d", bus.writes)
+	}
+	if on, _ := bus.led(0x40, 1); on != 4096 {
+		t.Error("channel 1 should be full on")
+	}
+	if _, off := bus.led(0x40, 3); off != 2047 {
+		t.Error("channel 3 should keep its value, got", off)
+	}
+	if _, off := bus.led(0x40, 4); off != 4096 {
+		t.Error("channel 4 should stay full off, got", off)
+	}
+	if _, off := bus.led(0x41, 15); off != 409 {
+		t.Error("channel 31 should map to the second chip, got", off)
+	}
+	ch, _ = pwm.PWMChannel(5)
+	if ch.(*pca9685Channel).v != 25 {
+		t.Error("batch should record channel values")
+	}
+
+	if err := batch.SetBatch(map[int]float64{2: 150}); err == nil {
+		t.Error("values above 100 should be rejected")
+	}
+	if err := batch.SetBatch(map[int]float64{32: 50}); err == nil {
+		t.Error("channels beyond the chain should be rejected")
+	}
+}
+
+func TestOutputConfiguration(t *testing.T) {
+	bus := newFakeBus()
+	p := map[string]interface{}{
+		"Address":           0x40,
+		"Frequency":         200,
+		"Invert Outputs":    true,
+		"Output Drive":      "open-drain",
+		"Output Change":     "ack",
+		"Output Disabled":   "high-impedance",
+		"Inverted Channels": "2, 7",
+	}
+	f := Factory()
+	if valid, failures := f.ValidateParameters(p); !valid {
+		t.Fatal(failures)
+	}
+	d, err := f.NewDriver(p, bus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := bus.chip(0x40)[mode2RegAddr]; mode != 0x1A {
+		t.Errorf("expected MODE2 0x1a, got %#x", mode)
+	}
+
+	pwm := d.(hal.PWMDriver)
+	ch, _ := pwm.PWMChannel(2)
+	if err := ch.Set(100); err != nil {
+		t.Fatal(err)
+	}
+	if _, off := bus.led(0x40, 2); off != 4096 {
+		t.Error("inverted channel at 100 should be full off, got", off)
+	}
+	if !ch.(hal.DigitalOutputPin).LastState() {
+		t.Error("inverted channel should report its logical state")
+	}
+	if err := ch.Set(25); err != nil {
+		t.Fatal(err)
+	}
+	if _, off := bus.led(0x40, 2); off != 3071 {
+		t.Error("inverted channel at 25 should output 75%, got", off)
+	}
+	ch, _ = pwm.PWMChannel(3)
+	if err := ch.Set(25); err != nil {
+		t.Fatal(err)
+	}
+	if _, off := bus.led(0x40, 3); off != 1023 {
+		t.Error("channel 3 should not be inverted, got", off)
+	}
+	if err := d.(BatchSetter).SetBatch(map[int]float64{7: 0}); err != nil {
+		t.Fatal(err)
+	}
+	if on, _ := bus.led(0x40, 7); on != 4096 {
+		t.Error("inverted channel at 0 should be full on")
+	}
+
+	// Defaults keep the power on totem-pole drive
+	bus = newFakeBus()
+	if _, err := f.NewDriver(params, bus); err != nil {
+		t.Fatal(err)
+	}
+	if mode := bus.chip(0x40)[mode2RegAddr]; mode != mode2TotemPole {
+		t.Errorf("expected default MODE2 0x04, got %#x", mode)
+	}
+}
+
+func TestOutputValidation(t *testing.T) {
+	f := Factory()
+	for param, v := range map[string]interface{}{
+		"Invert Outputs":    "yes",
+		"Output Drive":      "push-pull",
+		"Output Change":     1,
+		"Output Disabled":   "floating",
+		"Inverted Channels": "3,16",
+	} {
+		if valid, _ := f.ValidateParameters(map[string]interface{}{"Address": 0x40, "Frequency": 200, param: v}); valid {
+			t.Errorf("%s %v should be rejected", param, v)
+		}
+	}
+	p := map[string]interface{}{"Addresses": "0x40,0x41", "Frequency": 200, "Inverted Channels": "31"}
+	if valid, failures := f.ValidateParameters(p); !valid {
+		t.Error("channel 31 exists on two chips", failures)
+	}
+}
+
+func TestPhaseStagger(t *testing.T) {
+	bus := newFakeBus()
+	p := map[string]interface{}{
+		"Address":       0x40,
+		"Frequency":     200,
+		"Phase Stagger": true,
+	}
+	f := Factory()
+	d, err := f.NewDriver(p, bus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pwm := d.(hal.PWMDriver)
+	for _, c := range []struct {
+		ch      int
+		value   float64
+		on, off uint16
+	}{
+		{0, 50, 0, 2047},
+		{1, 50, 256, 2303},
+		{15, 50, 3840, 1791}, // wraps around past 4095
+		{15, 100, 4096, 0},
+		{14, 0, 0, 4096},
+	} {
+		ch, _ := pwm.PWMChannel(c.ch)
+		if err := ch.Set(c.value); err != nil {
+			t.Fatal(err)
+		}
+		if on, off := bus.led(0x40, c.ch); on != c.on || off != c.off {
+			t.Errorf("channel %d at %v: expected %d/%d, got %d/%d", c.ch, c.value, c.on, c.off, on, off)
+		}
+	}
+
+	bus = newFakeBus()
+	p = map[string]interface{}{
+		"Address":       0x40,
+		"Frequency":     200,
+		"Phase Stagger": true,
+		"Phase Offsets": "100, 4000",
+	}
+	if d, err = f.NewDriver(p, bus); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.(BatchSetter).SetBatch(map[int]float64{0: 10, 1: 10, 2: 10}); err != nil {
+		t.Fatal(err)
+	}
+	for ch, want := range []PWM{{100, 509}, {4000, 313}, {0, 409}} {
+		if on, off := bus.led(0x40, ch); on != want.On || off != want.Off {
+			t.Errorf("channel %d: expected %d/%d, got %d/%d", ch, want.On, want.Off, on, off)
+		}
+	}
+
+	for _, offsets := range []string{"4096", "1,x", "0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0"} {
+		if valid, _ := f.ValidateParameters(map[string]interface{}{"Address": 0x40, "Frequency": 200, "Phase Offsets": offsets}); valid {
+			t.Errorf("offsets %q should be rejected", offsets)
+		}
+	}
+}
+
+func TestServo(t *testing.T) {
+	bus := newFakeBus()
+	p := map[string]interface{}{
+		"Address":            0x40,
+		"Frequency":          50,
+		"Servo Channels":     "4",
+		"Servo Min Pulse":    500,
+		"Servo Center Pulse": 1400,
+		"Servo Max Pulse":    2500,
+	}
+	f := Factory()
+	if valid, failures := f.ValidateParameters(p); !valid {
+		t.Fatal(failures)
+	}
+	d, err := f.NewDriver(p, bus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pwm := d.(hal.PWMDriver)
+	ch, _ := pwm.PWMChannel(4)
+	servo, ok := ch.(*Servo)
+	if !ok {
+		t.Fatal("channel 4 should be a servo")
+	}
+	if _, ok := pwm.PWMChannels()[5].(*Servo); ok {
+		t.Error("only channel 4 should be a servo")
+	}
+	freq := d.(*pca9685Driver).chips[0].Frequency()
+	expect := func(us float64) {
+		t.Helper()
+		want := uint16(math.Round(us * freq * 4096 / 1e6))
+		if on, off := bus.led(0x40, 4); on != 0 || off != want {
+			t.Errorf("expected %vµs as 0/%d, got %d/%d", us, want, on, off)
+		}
+	}
+	if err := servo.SetPulse(1000); err != nil {
+		t.Fatal(err)
+	}
+	expect(1000)
+	if err := servo.SetAngle(90); err != nil {
+		t.Fatal(err)
+	}
+	expect(1400)
+	if err := servo.SetAngle(45); err != nil {
+		t.Fatal(err)
+	}
+	expect(950)
+	if err := servo.Set(100); err != nil {
+		t.Fatal(err)
+	}
+	expect(2500)
+	if !servo.LastState() {
+		t.Error("servo at max pulse should report on")
+	}
+	if err := servo.SetPulse(3000); err == nil {
+		t.Error("pulses beyond max should be rejected")
+	}
+	if err := servo.SetAngle(-1); err == nil {
+		t.Error("negative angles should be rejected")
+	}
+	if err := d.(BatchSetter).SetBatch(map[int]float64{4: 50}); err == nil {
+		t.Error("batches should not drive servos")
+	}
+
+	p["Servo Max Pulse"] = 20000
+	if valid, _ := f.ValidateParameters(p); valid {
+		t.Error("max pulse beyond the 50Hz period should be rejected")
+	}
+}
+
+func TestServoSpeed(t *testing.T) {
+	defer func(s time.Duration) { servoStep = s }(servoStep)
+	servoStep = time.Millisecond
+
+	p := map[string]interface{}{
+		"Address":        0x40,
+		"Frequency":      50,
+		"Servo Channels": "0",
+		"Servo Speed":    9000, // 50µs per step
+	}
+	bus := newFakeBus()
+	d, err := Factory().NewDriver(p, bus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch, _ := d.(hal.PWMDriver).PWMChannel(0)
+	servo := ch.(*Servo)
+	if err := servo.SetPulse(1000); err != nil {
+		t.Fatal(err)
+	}
+	if servo.Pulse() != 1000 {
+		t.Error("the first move should be immediate")
+	}
+	if err := servo.SetPulse(2000); err != nil {
+		t.Fatal(err)
+	}
+	if servo.Pulse() == 2000 {
+		t.Error("speed limited moves should not be immediate")
+	}
+	servo.Wait()
+	if v := servo.Pulse(); v != 2000 {
+		t.Error("expected to reach 2000µs, got", v)
+	}
+
+	if err := servo.SetPulse(1000); err != nil {
+		t.Fatal(err)
+	}
+	if err := servo.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// Close waits for the move to end, nothing is written afterwards
+	v, writes := servo.Pulse(), bus.writes
+	time.Sleep(5 * time.Millisecond)
+	if servo.Pulse() != v || v == 1000 {
+		t.Error("closing should stop the servo mid-way, got", v)
+	}
+	if bus.writes != writes {
+		t.Error("closing should stop writing to the servo")
+	}
+
+	for param, v := range map[string]interface{}{"Invert Outputs": true, "Inverted Channels": "0"} {
+		p := map[string]interface{}{"Address": 0x40, "Frequency": 50, "Servo Channels": "0", param: v}
+		if valid, _ := Factory().ValidateParameters(p); valid {
+			t.Error("servo channels should be refused with", param)
+		}
+	}
+}
+
+func TestCurves(t *testing.T) {
+	for _, c := range []struct {
+		curve Curve
+		in    float64
+		out   float64
+	}{
+		{Linear, 10, 10},
+		{CIE1931, 0, 0},
+		{CIE1931, 8, 0.8856},
+		{CIE1931, 10, 1.1260},
+		{CIE1931, 100, 100},
+		{Gamma(2), 50, 25},
+		{Gamma(2.2), 100, 100},
+	} {
+		if v := c.curve(c.in); math.Abs(v-c.out) > 0.001 {
+			t.Errorf("curve at %v: expected %v, got %v", c.in, c.out, v)
+		}
+	}
+	table, err := Table([]float64{0, 10, 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := table(25); v != 5 {
+		t.Error("expected 5, got", v)
+	}
+	if v := table(75); v != 55 {
+		t.Error("expected 55, got", v)
+	}
+	if _, err := Table([]float64{0, 50}); err == nil {
+		t.Error("tables must end at 100")
+	}
+
+	bus := newFakeBus()
+	p := map[string]interface{}{
+		"Address":        0x40,
+		"Frequency":      200,
+		"Curve":          "gamma 2",
+		"Curve Table":    "0, 10, 100",
+		"Channel Curves": "1=linear; 2,3=table",
+	}
+	f := Factory()
+	if valid, failures := f.ValidateParameters(p); !valid {
+		t.Fatal(failures)
+	}
+	d, err := f.NewDriver(p, bus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pwm := d.(hal.PWMDriver)
+	for ch, off := range []uint16{1023, 2047, 409, 409} {
+		c, _ := pwm.PWMChannel(ch)
+		if err := c.Set(50); err != nil {
+			t.Fatal(err)
+		}
+		if _, v := bus.led(0x40, ch); v != off {
+			t.Errorf("channel %d: expected off count %d, got %d", ch, off, v)
+		}
+	}
+
+	for param, v := range map[string]interface{}{
+		"Curve":          "gamma x",
+		"Channel Curves": "1:linear",
+	} {
+		if valid, _ := f.ValidateParameters(map[string]interface{}{"Address": 0x40, "Frequency": 200, param: v}); valid {
+			t.Errorf("%s %v should be rejected", param, v)
+		}
+	}
+	if valid, _ := f.ValidateParameters(map[string]interface{}{"Address": 0x40, "Frequency": 200, "Curve": "table", "Curve Table": "0,200,100"}); valid {
+		t.Error("table points above 100 should be rejected")
+	}
+}
+
+func TestFade(t *testing.T) {
+	defer func(s time.Duration) { fadeStep = s }(fadeStep)
+	fadeStep = time.Millisecond
+
+	bus := newFakeBus()
+	d, err := Factory().NewDriver(params, bus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch, _ := d.(hal.PWMDriver).PWMChannel(0)
+	fader := ch.(Fader)
+	value := func() float64 {
+		c := ch.(*pca9685Channel)
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return c.v
+	}
+
+	if err := fader.Fade(100, 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 1000 && value() != 100; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if on, _ := bus.led(0x40, 0); on != 4096 {
+		t.Error("fade should end full on")
+	}
+
+	if err := fader.Fade(0, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 1000 && value() == 100; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	fader.StopFade()
+	v := value()
+	if v == 100 || v < 99 {
+		t.Error("fade should move slowly from 100, got", v)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if value() != v {
+		t.Error("a stopped fade should not update the channel")
+	}
+
+	if err := fader.Fade(0, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := ch.Set(30); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if value() != 30 {
+		t.Error("setting a value should cancel the fade")
+	}
+	if err := fader.Fade(120, time.Second); err == nil {
+		t.Error("targets above 100 should be rejected")
+	}
+}
+
+func TestRestore(t *testing.T) {
+	bus := newFakeBus()
+	regs := bus.chip(0x40)
+	regs[mode1RegAddr], regs[preScaleRegAddr] = 0x21, 29
+	for ch, pwm := range []PWM{{4096, 0}, {0, 2047}, {3840, 1791}, {0, 4096}, {0, 1023}, {0, 1250}} {
+		r := regs[pwm0OnLowReg+4*ch:]
+		r[0], r[1], r[2], r[3] = byte(pwm.On), byte(pwm.On>>8), byte(pwm.Off), byte(pwm.Off>>8)
+	}
+	p := map[string]interface{}{
+		"Address":           0x40,
+		"Frequency":         200,
+		"Keep Outputs":      true,
+		"Inverted Channels": "4",
+		"Servo Channels":    "5",
+		"Servo Max Pulse":   2000,
+		"Curve":             "gamma 2",
+	}
+	f := Factory()
+	if valid, failures := f.ValidateParameters(p); !valid {
+		t.Fatal(failures)
+	}
+	d, err := f.NewDriver(p, bus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range bus.mode1 {
+		if m&mode1Sleep != 0 {
+			t.Error("a running chip should not be put to sleep")
+		}
+	}
+	if _, off := bus.led(0x40, 1); off != 2047 {
+		t.Error("restoring should not change the outputs")
+	}
+
+	pwm := d.(*pca9685Driver)
+	for ch, want := range []float64{100, 70.7, 70.7, 0, 86.6} {
+		c := pwm.channels[ch]
+		if math.Abs(c.v-want) > 0.1 {
+			t.Errorf("channel %d: expected %v, got %v", ch, want, c.v)
+		}
+	}
+	if !pwm.channels[0].LastState() {
+		t.Error("channel 0 should be restored on")
+	}
+	if v := pwm.servos[5].Pulse(); math.Abs(v-1500) > 5 {
+		t.Error("servo should be restored at 1500µs, got", v)
+	}
+	if freq, _ := pwm.chips[0].ReadFrequency(); math.Abs(freq-203.45) > 0.01 {
+		t.Error("expected 203.45Hz, got", freq)
+	}
+
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if on, _ := bus.led(0x40, 0); on != 4096 || regs[mode1RegAddr]&mode1Sleep != 0 {
+		t.Error("closing should keep the outputs running")
+	}
+
+	// A frequency change needs the chip to sleep, the outputs are restored
+	// from the registers all the same
+	bus.mode1 = nil
+	p["Frequency"] = 100
+	if d, err = f.NewDriver(p, bus); err != nil {
+		t.Fatal(err)
+	}
+	if bus.mode1[0]&mode1Sleep == 0 {
+		t.Error("changing the frequency should put the chip to sleep")
+	}
+	if m := regs[mode1RegAddr]; m&mode1Sleep != 0 || m&mode1AutoIncrement == 0 {
+		t.Errorf("expected the chip awake with auto-increment, got MODE1 %#x", m)
+	}
+	if !d.(*pca9685Driver).channels[0].LastState() {
+		t.Error("channel 0 should be restored on")
+	}
+}
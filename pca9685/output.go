@@ -0,0 +1,141 @@
+package pca9685
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	mode2RegAddr = 0x01
+
+	mode2Invert    = 0x10
+	mode2OCHAck    = 0x08
+	mode2TotemPole = 0x04
+)
+
+const (
+	invertParam        = "Invert Outputs"
+	driveParam         = "Output Drive"
+	changeParam        = "Output Change"
+	disabledParam      = "Output Disabled"
+	invertChannelParam = "Inverted Channels"
+)
+
+// Output drive options, the OUTDRV bit of MODE2
+var driveOptions = map[string]byte{
+	"totem-pole": mode2TotemPole,
+	"open-drain": 0,
+}
+
+// Output change options, the OCH bit of MODE2: outputs change on the I2C
+// STOP condition or on the ACK of each byte
+var changeOptions = map[string]byte{
+	"stop": 0,
+	"ack":  mode2OCHAck,
+}
+
+// Outputs while OE is high, the OUTNE bits of MODE2. "high" drives the
+// outputs high with a totem-pole drive and leaves them floating when open-drain.
+var disabledOptions = map[string]byte{
+	"low":            0x00,
+	"high":           0x01,
+	"high-impedance": 0x02,
+}
+
+func parseOption(v interface{}, options map[string]byte) (byte, error) {
+	s, ok := v.(string)
+	if !ok {
+		return 0, fmt.Errorf(" is not a string. %v was received.", v)
+	}
+	b, ok := options[s]
+	if !ok {
+		var valid []string
+		for o := range options {
+			valid = append(valid, o)
+		}
+		sort.Strings(valid)
+		return 0, fmt.Errorf(" is not a valid value of %s. %v was received.", strings.Join(valid, ", "), v)
+	}
+	return b, nil
+}
+
+// mode2 returns the MODE2 register value for validated parameters
+func mode2(parameters map[string]interface{}) byte {
+	var mode byte = mode2TotemPole
+	if invert, _ := parameters[invertParam].(bool); invert {
+		mode |= mode2Invert
+	}
+	if v, ok := parameters[driveParam]; ok {
+		drive, _ := parseOption(v, driveOptions)
+		mode = mode&^mode2TotemPole | drive
+	}
+	if v, ok := parameters[changeParam]; ok {
+		change, _ := parseOption(v, changeOptions)
+		mode |= change
+	}
+	if v, ok := parameters[disabledParam]; ok {
+		disabled, _ := parseOption(v, disabledOptions)
+		mode |= disabled
+	}
+	return mode
+}
+
+func (f *pcaFactory) validateOutput(parameters map[string]interface{}, failures map[string][]string) {
+	if v, ok := parameters[invertParam]; ok {
+		if _, ok := v.(bool); !ok {
+			failure := fmt.Sprint(invertParam, " is not a boolean. ", v, " was received.")
+			failures[invertParam] = append(failures[invertParam], failure)
+		}
+	}
+	for param, options := range map[string]map[string]byte{
+		driveParam:    driveOptions,
+		changeParam:   changeOptions,
+		disabledParam: disabledOptions,
+	} {
+		if v, ok := parameters[param]; ok {
+			if _, err := parseOption(v, options); err != nil {
+				failures[param] = append(failures[param], fmt.Sprint(param, err.Error()))
+			}
+		}
+	}
+	if v, ok := parameters[invertChannelParam]; ok {
+		if _, err := parseChannels(v, chainLength(parameters)*numChannels); err != nil {
+			failures[invertChannelParam] = append(failures[invertChannelParam], fmt.Sprint(invertChannelParam, err.Error()))
+		}
+	}
+}
+
+// parseChannels parses a comma or space separated list of channel numbers
+// below n. An absent or empty list is valid.
+func parseChannels(v interface{}, n int) ([]int, error) {
+	fields, err := parseList(v)
+	if err != nil {
+		return nil, err
+	}
+	var chs []int
+	for _, f := range fields {
+		ch, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, fmt.Errorf(" has an invalid channel %q.", f)
+		}
+		if ch < 0 || ch >= n {
+			return nil, fmt.Errorf(" channel is out of range (0 - %d). %v was received.", n-1, f)
+		}
+		chs = append(chs, ch)
+	}
+	return chs, nil
+}
+
+// parseList splits a comma or space separated string parameter
+func parseList(v interface{}) ([]string, error) {
+	if v == nil {
+		return nil, nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf(" is not a string. %v was received.", v)
+	}
+	return strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }), nil
+}
@@ -1,7 +1,7 @@
 package pca9685
 
 import (
-	"log"
+	"fmt"
 	"math"
 	"time"
 
@@ -14,26 +14,128 @@ const (
 	mode1RegAddr     = 0x00
 	preScaleRegAddr  = 0xFE
 	pwm0OnLowReg     = 0x6
-	defaultFreq      = 490
+	allLedOnLowReg   = 0xFA
+	allCallAddr      = 0x70
+	numChannels      = 16
+
+	mode1Restart       = 0x80
+	mode1AutoIncrement = 0x20
+	mode1Sleep         = 0x10
+	ledFullOn          = 0x1000
+	defaultFreq        = 490
 )
 
+// PWM holds the on and off counts of a channel, each within 0-4095. Bit 12
+// (0x1000) turns the channel fully on or fully off.
+type PWM struct {
+	On, Off uint16
+}
+
 type PCA9685 struct {
-	addr byte
-	bus  i2c.Bus
-	Freq int
+	addr  byte
+	bus   i2c.Bus
+	Freq  int
+	Mode2 byte
+	leds  [numChannels]PWM
 }
 
 func New(addr byte, bus i2c.Bus) *PCA9685 {
-	return &PCA9685{
-		addr: addr,
-		bus:  bus,
-		Freq: defaultFreq,
+	p := &PCA9685{
+		addr:  addr,
+		bus:   bus,
+		Freq:  defaultFreq,
+		Mode2: mode2TotemPole,
+	}
+	for i := range p.leds {
+		p.leds[i] = PWM{Off: ledFullOn}
 	}
+	return p
 }
 
 func (p *PCA9685) mode1Reg() (byte, error) {
 	mode1Reg := make([]byte, 1)
-	return mode1Reg[0], p.bus.WriteToReg(p.addr, mode1RegAddr, mode1Reg)
+	if err := p.bus.ReadFromReg(p.addr, mode1RegAddr, mode1Reg); err != nil {
+		return 0, err
+	}
+	return mode1Reg[0], nil
+}
+
+// ReadMode returns the MODE1 and MODE2 registers
+func (p *PCA9685) ReadMode() (byte, byte, error) {
+	mode1, err := p.mode1Reg()
+	if err != nil {
+		return 0, 0, err
+	}
+	mode2 := make([]byte, 1)
+	if err := p.bus.ReadFromReg(p.addr, mode2RegAddr, mode2); err != nil {
+		return 0, 0, err
+	}
+	return mode1, mode2[0], nil
+}
+
+// ReadPrescale returns the PRE_SCALE register
+func (p *PCA9685) ReadPrescale() (byte, error) {
+	pre := make([]byte, 1)
+	if err := p.bus.ReadFromReg(p.addr, preScaleRegAddr, pre); err != nil {
+		return 0, err
+	}
+	return pre[0], nil
+}
+
+// ReadFrequency returns the PWM frequency set in the PRE_SCALE register
+func (p *PCA9685) ReadFrequency() (float64, error) {
+	pre, err := p.ReadPrescale()
+	if err != nil {
+		return 0, err
+	}
+	return clockFreq / (pwmControlPoints * (float64(pre) + 1)), nil
+}
+
+// ReadPwms returns the on and off counts of every channel, read in a single
+// burst. It requires auto-increment, which Wake and Resume enable.
+func (p *PCA9685) ReadPwms() ([]PWM, error) {
+	buf := make([]byte, 4*numChannels)
+	if err := p.bus.ReadFromReg(p.addr, pwm0OnLowReg, buf); err != nil {
+		return nil, err
+	}
+	pwms := make([]PWM, numChannels)
+	for i := range pwms {
+		b := buf[4*i:]
+		pwms[i] = PWM{
+			On:  uint16(b[0]) | uint16(b[1])<<8,
+			Off: uint16(b[2]) | uint16(b[3])<<8,
+		}
+	}
+	copy(p.leds[:], pwms)
+	return pwms, nil
+}
+
+// Resume takes over a chip that is already running at the configured
+// frequency, without interrupting its outputs. It returns false, leaving the
+// chip untouched, when the chip is asleep or its prescaler differs, in which
+// case Wake is needed.
+func (p *PCA9685) Resume() (bool, error) {
+	mode1, mode2, err := p.ReadMode()
+	if err != nil {
+		return false, err
+	}
+	pre, err := p.ReadPrescale()
+	if err != nil {
+		return false, err
+	}
+	if p.Freq == 0 {
+		p.Freq = defaultFreq
+	}
+	if mode1&mode1Sleep != 0 || pre != p.prescale() {
+		return false, nil
+	}
+	if mode2 != p.Mode2 {
+		if err := p.bus.WriteToReg(p.addr, mode2RegAddr, []byte{p.Mode2}); err != nil {
+			return false, err
+		}
+	}
+	newmode := mode1&^mode1Restart | 0x01 | mode1AutoIncrement
+	return true, p.bus.WriteToReg(p.addr, mode1RegAddr, []byte{newmode})
 }
 
 // Set the sleep flag on the PCA. This will shut down the oscillators.
@@ -58,10 +160,13 @@ func (p *PCA9685) Wake() error {
 	if p.Freq == 0 {
 		p.Freq = defaultFreq
 	}
-	preScaleValue := byte(math.Floor(float64(clockFreq/(pwmControlPoints*p.Freq))+float64(0.5)) - 1)
+	preScaleValue := p.prescale()
 	if err := p.bus.WriteToReg(p.addr, preScaleRegAddr, []byte{preScaleValue}); err != nil {
 		return err
 	}
+	if err := p.bus.WriteToReg(p.addr, mode2RegAddr, []byte{p.Mode2}); err != nil {
+		return err
+	}
 	wakeMode := mode1Reg & 0xEF
 	if (mode1Reg & 0x80) == 0x80 {
 		if err := p.bus.WriteToReg(p.addr, mode1RegAddr, []byte{wakeMode}); err != nil {
@@ -75,38 +180,54 @@ func (p *PCA9685) Wake() error {
 		return err
 	}
 
-	newmode := ((mode1Reg | 0x01) & 0xDF)
+	// Respond to ALL_CALL and auto-increment the register address so a
+	// channel, or a run of channels, is written in a single transaction
+	newmode := mode1Reg&^(mode1Restart|mode1Sleep) | 0x01 | mode1AutoIncrement
 	return p.bus.WriteToReg(p.addr, mode1RegAddr, []byte{newmode})
 }
 
+func (p *PCA9685) prescale() byte {
+	return byte(math.Floor(float64(clockFreq/(pwmControlPoints*p.Freq))+float64(0.5)) - 1)
+}
+
+// Frequency returns the PWM frequency actually produced by the prescaler,
+// which differs slightly from Freq.
+func (p *PCA9685) Frequency() float64 {
+	return clockFreq / (pwmControlPoints * (float64(p.prescale()) + 1))
+}
+
 func (p *PCA9685) SetPwm(channel int, onTime, offTime uint16) error {
-	log.Println("onTime ", onTime, " offTime ", offTime)
-	// Split the ints into 4 bytes
-	timeReg := byte(pwm0OnLowReg + (4 * channel))
-	onTimeLow := byte(onTime & 0xFF)
-	onTimeHigh := byte(onTime >> 8)
-	offTimeLow := byte(offTime & 0xFF)
-	offTimeHigh := byte(offTime >> 8)
-
-	//log.Println("onLow ", onTimeLow, " onHigh ", onTimeHigh, " offLow ", offTimeLow, " offHigh ", offTimeHigh)
-	if err := p.bus.WriteToReg(p.addr, timeReg, []byte{onTimeLow}); err != nil {
-		return err
+	return p.SetPwms(channel, []PWM{{On: onTime, Off: offTime}})
+}
+
+// SetPwms writes consecutive channels, starting at first, in a single burst
+// so that they all change in the same PWM period.
+func (p *PCA9685) SetPwms(first int, pwms []PWM) error {
+	if first < 0 || first+len(pwms) > numChannels {
+		return fmt.Errorf("invalid channel range %d-%d", first, first+len(pwms)-1)
 	}
-	if err := p.bus.WriteToReg(p.addr, timeReg+1, []byte{onTimeHigh}); err != nil {
-		return err
+	buf := make([]byte, 0, 4*len(pwms))
+	for _, pwm := range pwms {
+		buf = append(buf, byte(pwm.On), byte(pwm.On>>8), byte(pwm.Off), byte(pwm.Off>>8))
 	}
-	if err := p.bus.WriteToReg(p.addr, timeReg+2, []byte{offTimeLow}); err != nil {
+	if err := p.bus.WriteToReg(p.addr, byte(pwm0OnLowReg+4*first), buf); err != nil {
 		return err
 	}
-	return p.bus.WriteToReg(p.addr, timeReg+3, []byte{offTimeHigh})
+	copy(p.leds[first:], pwms)
+	return nil
 }
 
 func (p *PCA9685) Close() error {
 	// Clear all channels to full off
-	for regAddr := 0x06; regAddr < 0x50; regAddr += 4 {
-		if err := p.bus.WriteToReg(p.addr, byte(regAddr), []byte{0x00, 0x00, 0x00, 0x10}); err != nil {
-			return err
-		}
+	off := make([]PWM, numChannels)
+	for i := range off {
+		off[i] = PWM{Off: ledFullOn}
 	}
-	return nil
+	return p.SetPwms(0, off)
+}
+
+// allCallOff turns off the outputs of every chip on the bus that responds to
+// the default ALL_CALL address.
+func allCallOff(bus i2c.Bus) error {
+	return bus.WriteToReg(allCallAddr, allLedOnLowReg, []byte{0x00, 0x00, 0x00, 0x10})
 }
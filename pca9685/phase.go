@@ -0,0 +1,87 @@
+package pca9685
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	staggerParam = "Phase Stagger"
+	offsetsParam = "Phase Offsets"
+)
+
+// phaseOffsets returns the on-time offset of each of n channels for validated
+// parameters. Explicit offsets take precedence over an even stagger across
+// the chain, and channels without an offset switch on at count 0.
+func phaseOffsets(parameters map[string]interface{}, n int) []uint16 {
+	offsets := make([]uint16, n)
+	if explicit, _ := parseOffsets(parameters[offsetsParam], n); len(explicit) > 0 {
+		copy(offsets, explicit)
+		return offsets
+	}
+	if stagger, _ := parameters[staggerParam].(bool); stagger {
+		for i := range offsets {
+			offsets[i] = uint16(i * pwmControlPoints / n)
+		}
+	}
+	return offsets
+}
+
+// parseOffsets parses a comma or space separated list of on-time offsets in
+// counts (0 - 4095), one per channel starting at channel 0.
+func parseOffsets(v interface{}, n int) ([]uint16, error) {
+	fields, err := parseList(v)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) > n {
+		return nil, fmt.Errorf(" has %d offsets for %d channels.", len(fields), n)
+	}
+	var offsets []uint16
+	for _, f := range fields {
+		o, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, fmt.Errorf(" has an invalid offset %q.", f)
+		}
+		if o < 0 || o >= pwmControlPoints {
+			return nil, fmt.Errorf(" offset is out of range (0 - 4095). %v was received.", f)
+		}
+		offsets = append(offsets, uint16(o))
+	}
+	return offsets, nil
+}
+
+func (f *pcaFactory) validatePhase(parameters map[string]interface{}, failures map[string][]string) {
+	if v, ok := parameters[staggerParam]; ok {
+		if _, ok := v.(bool); !ok {
+			failure := fmt.Sprint(staggerParam, " is not a boolean. ", v, " was received.")
+			failures[staggerParam] = append(failures[staggerParam], failure)
+		}
+	}
+	if v, ok := parameters[offsetsParam]; ok {
+		if _, err := parseOffsets(v, chainLength(parameters)*numChannels); err != nil {
+			failures[offsetsParam] = append(failures[offsetsParam], fmt.Sprint(offsetsParam, err.Error()))
+		}
+	}
+}
+
+// chainLength returns the number of chips configured by parameters
+func chainLength(parameters map[string]interface{}) int {
+	if addrs, _ := parseAddresses(parameters[addressesParam]); len(addrs) > 0 {
+		return len(addrs)
+	}
+	return 1
+}
+
+// shift moves the on-time of a channel by offset counts while keeping its
+// duty cycle. The off-time wraps around past 4095, which the chip handles by
+// keeping the output on across the end of the period.
+func shift(pwm PWM, offset uint16) PWM {
+	if offset == 0 || pwm.On&ledFullOn != 0 || pwm.Off&ledFullOn != 0 {
+		return pwm
+	}
+	return PWM{
+		On:  offset,
+		Off: (pwm.Off - pwm.On + offset) % pwmControlPoints,
+	}
+}
@@ -0,0 +1,75 @@
+package pca9685
+
+import "fmt"
+
+const keepParam = "Keep Outputs"
+
+// restore reads the LED registers of every chip and records the value of
+// each channel, so that the driver picks up from what the chips output.
+func (p *pca9685Driver) restore() error {
+	for chip, hwDriver := range p.chips {
+		pwms, err := hwDriver.ReadPwms()
+		if err != nil {
+			return err
+		}
+		for i, pwm := range pwms {
+			pin := chip*numChannels + i
+			if s, ok := p.servos[pin]; ok {
+				s.pulse = float64(countsOf(pwm)) * 1e6 / (hwDriver.Frequency() * pwmControlPoints)
+				s.target = s.pulse
+				continue
+			}
+			duty := float64(countsOf(pwm)) / 40.95
+			ch := p.channels[pin]
+			if ch.inverted {
+				duty = 100 - duty
+			}
+			if ch.curve != nil {
+				duty = inverse(ch.curve, duty)
+			}
+			ch.v = duty
+		}
+	}
+	return nil
+}
+
+// countsOf returns how many counts of the period a channel is on, from 0 to
+// 4095 as written by the driver for full off and full on.
+func countsOf(pwm PWM) uint16 {
+	switch {
+	case pwm.Off&ledFullOn != 0:
+		return 0
+	case pwm.On&ledFullOn != 0:
+		return 4095
+	}
+	return (pwm.Off - pwm.On) % pwmControlPoints
+}
+
+// inverse finds the value a monotonic curve maps to duty
+func inverse(curve Curve, duty float64) float64 {
+	lo, hi := 0.0, 100.0
+	if duty <= curve(lo) {
+		return lo
+	}
+	if duty >= curve(hi) {
+		return hi
+	}
+	for i := 0; i < 50; i++ {
+		mid := (lo + hi) / 2
+		if curve(mid) < duty {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
+
+func (f *pcaFactory) validateKeep(parameters map[string]interface{}, failures map[string][]string) {
+	if v, ok := parameters[keepParam]; ok {
+		if _, ok := v.(bool); !ok {
+			failure := fmt.Sprint(keepParam, " is not a boolean. ", v, " was received.")
+			failures[keepParam] = append(failures[keepParam], failure)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package pca9685
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/reef-pi/hal"
+)
+
+const (
+	servoChannelsParam = "Servo Channels"
+	servoMinParam      = "Servo Min Pulse"
+	servoMaxParam      = "Servo Max Pulse"
+	servoCenterParam   = "Servo Center Pulse"
+	servoRangeParam    = "Servo Range"
+	servoSpeedParam    = "Servo Speed"
+)
+
+// servoStep is the interval between position updates of a speed limited
+// servo, one period of a 50Hz servo signal.
+var servoStep = 20 * time.Millisecond
+
+// ServoConfig describes the pulse widths accepted by a servo.
+type ServoConfig struct {
+	// MinPulse, CenterPulse and MaxPulse are the pulse widths in microseconds
+	// at angle 0, Range/2 and Range.
+	MinPulse, CenterPulse, MaxPulse float64
+	// Range is the travel of the servo in degrees.
+	Range float64
+	// Speed limits the travel in degrees per second. Zero moves at once.
+	Speed float64
+}
+
+func (c ServoConfig) validate(frequency float64) error {
+	switch {
+	case c.MinPulse <= 0:
+		return fmt.Errorf("min pulse must be positive. %v was received", c.MinPulse)
+	case c.CenterPulse <= c.MinPulse || c.MaxPulse <= c.CenterPulse:
+		return fmt.Errorf("pulses must satisfy min < center < max. %v, %v, %v were received", c.MinPulse, c.CenterPulse, c.MaxPulse)
+	case c.MaxPulse >= 1e6/frequency:
+		return fmt.Errorf("max pulse %vµs does not fit in the %.0fHz period", c.MaxPulse, frequency)
+	case c.Range <= 0:
+		return fmt.Errorf("range must be positive. %v was received", c.Range)
+	case c.Speed < 0:
+		return fmt.Errorf("speed can not be negative. %v was received", c.Speed)
+	}
+	return nil
+}
+
+// Servo is a channel driving a hobby servo, positioned by pulse width or
+// angle. It implements hal.PWMChannel, mapping 0-100 to the full travel.
+type Servo struct {
+	driver  *pca9685Driver
+	channel int
+	mu      sync.Mutex
+	cfg     ServoConfig
+	pulse   float64
+	target  float64
+	cancel  chan struct{}
+	done    chan struct{}
+}
+
+func (s *Servo) Name() string { return fmt.Sprintf("%d", s.channel) }
+func (s *Servo) Number() int  { return s.channel }
+
+// Close stops any motion in progress and waits for it to end, so that the
+// servo is not written to afterwards.
+func (s *Servo) Close() error {
+	s.mu.Lock()
+	done := s.done
+	s.stop()
+	s.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+	return nil
+}
+
+// Configure replaces the pulse widths, range and speed of the servo.
+func (s *Servo) Configure(cfg ServoConfig) error {
+	if err := cfg.validate(s.driver.chips[s.channel/numChannels].Frequency()); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cfg = cfg
+	return nil
+}
+
+// Set moves the servo to value percent of its travel.
+func (s *Servo) Set(value float64) error {
+	if value < 0 || value > 100 {
+		return fmt.Errorf("invalid value: %f outside 0-100", value)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.move(s.angleToPulse(value / 100 * s.cfg.Range))
+}
+
+// Write moves the servo to the end of its travel, or back to the start.
+func (s *Servo) Write(b bool) error {
+	var v float64
+	if b {
+		v = 100
+	}
+	return s.Set(v)
+}
+
+// LastState reports whether the servo was sent to the end of its travel.
+func (s *Servo) LastState() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.target == s.cfg.MaxPulse
+}
+
+// SetAngle moves the servo to angle degrees, within 0 and the servo range.
+func (s *Servo) SetAngle(angle float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if angle < 0 || angle > s.cfg.Range {
+		return fmt.Errorf("invalid angle: %v outside 0-%v", angle, s.cfg.Range)
+	}
+	return s.move(s.angleToPulse(angle))
+}
+
+// SetPulse moves the servo to a pulse width in microseconds, within the
+// min and max pulse.
+func (s *Servo) SetPulse(us float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if us < s.cfg.MinPulse || us > s.cfg.MaxPulse {
+		return fmt.Errorf("invalid pulse: %vµs outside %v-%vµs", us, s.cfg.MinPulse, s.cfg.MaxPulse)
+	}
+	return s.move(us)
+}
+
+// Pulse returns the pulse width currently output, in microseconds. It lags
+// behind the target while a speed limited move is in progress.
+func (s *Servo) Pulse() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pulse
+}
+
+// Wait blocks until the servo reaches its target.
+func (s *Servo) Wait() {
+	s.mu.Lock()
+	done := s.done
+	s.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+}
+
+func (s *Servo) angleToPulse(angle float64) float64 {
+	half := s.cfg.Range / 2
+	if angle <= half {
+		return s.cfg.MinPulse + (s.cfg.CenterPulse-s.cfg.MinPulse)*angle/half
+	}
+	return s.cfg.CenterPulse + (s.cfg.MaxPulse-s.cfg.CenterPulse)*(angle-half)/half
+}
+
+// move sends the servo towards us, directly or in steps limited by the
+// speed. The caller must hold s.mu.
+func (s *Servo) move(us float64) error {
+	s.stop()
+	s.target = us
+	if s.cfg.Speed == 0 || s.pulse == 0 {
+		return s.output(us)
+	}
+	rate := s.cfg.Speed * (s.cfg.MaxPulse - s.cfg.MinPulse) / s.cfg.Range
+	interval := servoStep
+	step := rate * interval.Seconds()
+	cancel, done := make(chan struct{}), make(chan struct{})
+	s.cancel, s.done = cancel, done
+	go func() {
+		defer close(done)
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-cancel:
+				return
+			case <-t.C:
+			}
+			s.mu.Lock()
+			select {
+			case <-cancel:
+				s.mu.Unlock()
+				return
+			default:
+			}
+			next := s.target
+			if d := next - s.pulse; math.Abs(d) > step {
+				next = s.pulse + math.Copysign(step, d)
+			}
+			err := s.output(next)
+			s.mu.Unlock()
+			if err != nil || next == s.target {
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// stop cancels a move in progress. The caller must hold s.mu.
+func (s *Servo) stop() {
+	if s.cancel != nil {
+		close(s.cancel)
+		s.cancel, s.done = nil, nil
+	}
+}
+
+// output writes the pulse as raw counts. The pulse is still moved by the
+// phase offset of the channel, which leaves its width unchanged.
+func (s *Servo) output(us float64) error {
+	hwDriver := s.driver.chips[s.channel/numChannels]
+	off := uint16(math.Round(us * hwDriver.Frequency() * pwmControlPoints / 1e6))
+	if err := s.driver.write(s.channel, PWM{Off: off}); err != nil {
+		return err
+	}
+	s.pulse = us
+	return nil
+}
+
+func servoConfig(parameters map[string]interface{}) ServoConfig {
+	cfg := ServoConfig{MinPulse: 1000, CenterPulse: 1500, MaxPulse: 2000, Range: 180}
+	for param, field := range map[string]*float64{
+		servoMinParam:    &cfg.MinPulse,
+		servoCenterParam: &cfg.CenterPulse,
+		servoMaxParam:    &cfg.MaxPulse,
+		servoRangeParam:  &cfg.Range,
+		servoSpeedParam:  &cfg.Speed,
+	} {
+		if v, ok := parameters[param]; ok {
+			i, _ := hal.ConvertToInt(v)
+			*field = float64(i)
+		}
+	}
+	return cfg
+}
+
+func (f *pcaFactory) validateServo(parameters map[string]interface{}, failures map[string][]string) {
+	if v, ok := parameters[servoChannelsParam]; ok {
+		if _, err := parseChannels(v, chainLength(parameters)*numChannels); err != nil {
+			failures[servoChannelsParam] = append(failures[servoChannelsParam], fmt.Sprint(servoChannelsParam, err.Error()))
+		}
+	}
+	servos, _ := parseChannels(parameters[servoChannelsParam], chainLength(parameters)*numChannels)
+	valid := len(servos) > 0
+	// Servo pulses are written as raw counts, which inversion would turn
+	// into the complementary pulse
+	if invert, _ := parameters[invertParam].(bool); invert && valid {
+		failure := fmt.Sprint(servoChannelsParam, " can not be used with ", invertParam, ".")
+		failures[servoChannelsParam] = append(failures[servoChannelsParam], failure)
+	}
+	inverted, _ := parseChannels(parameters[invertChannelParam], chainLength(parameters)*numChannels)
+	for _, ch := range inverted {
+		for _, servo := range servos {
+			if ch == servo {
+				failure := fmt.Sprint(servoChannelsParam, " channel ", ch, " can not be inverted.")
+				failures[servoChannelsParam] = append(failures[servoChannelsParam], failure)
+			}
+		}
+	}
+	for _, param := range []string{servoMinParam, servoCenterParam, servoMaxParam, servoRangeParam, servoSpeedParam} {
+		if v, ok := parameters[param]; ok {
+			if _, ok := hal.ConvertToInt(v); !ok {
+				failure := fmt.Sprint(param, " is not a number. ", v, " was received.")
+				failures[param] = append(failures[param], failure)
+				valid = false
+			}
+		}
+	}
+	freq, ok := hal.ConvertToInt(parameters[freqParam])
+	if !valid || !ok || freq <= 0 {
+		return
+	}
+	hw := PCA9685{Freq: freq}
+	if err := servoConfig(parameters).validate(hw.Frequency()); err != nil {
+		failures[servoChannelsParam] = append(failures[servoChannelsParam], fmt.Sprint(servoChannelsParam, " ", err.Error()))
+	}
+}
@@ -2,16 +2,17 @@ package sht3x
 
 import (
 	"fmt"
+
 	"github.com/reef-pi/hal"
 )
 
 type channel struct {
 	calibrator hal.Calibrator
-	d          *SHT31D
+	r          *measurement
 	number     int
 }
 
-func newChannel(d *SHT31D, i int) (hal.AnalogInputPin, error) {
+func newChannel(r *measurement, i int) (hal.AnalogInputPin, error) {
 	c, err := hal.CalibratorFactory([]hal.Measurement{})
 	if err != nil {
 		return nil, err
@@ -19,16 +20,22 @@ func newChannel(d *SHT31D, i int) (hal.AnalogInputPin, error) {
 	return &channel{
 		calibrator: c,
 		number:     i,
-		d:          d,
+		r:          r,
 	}, nil
 }
 
 func (c *channel) Name() string {
 	switch c.number {
-	case 0:
+	case temperatureChannel:
 		return "temperature"
-	case 1:
+	case humidityChannel:
 		return "humidity"
+	case dewPointChannel:
+		return "dew point"
+	case absoluteHumidityChannel:
+		return "absolute humidity"
+	case vpdChannel:
+		return "vapor pressure deficit"
 	default:
 		return "unknown"
 	}
@@ -46,12 +53,24 @@ func (c *channel) Calibrate(points []hal.Measurement) error {
 	return nil
 }
 
+// Value returns the temperature, humidity or the value derived from them,
+// measuring the sensor again once the last pair is older than _measurementAge
 func (c *channel) Value() (float64, error) {
+	t, rh, err := c.r.get()
+	if err != nil {
+		return 0, err
+	}
 	switch c.number {
-	case 0:
-		return c.d.Temperature()
-	case 1:
-		return c.d.Humidity()
+	case temperatureChannel:
+		return t, nil
+	case humidityChannel:
+		return rh, nil
+	case dewPointChannel:
+		return DewPoint(t, rh)
+	case absoluteHumidityChannel:
+		return AbsoluteHumidity(t, rh), nil
+	case vpdChannel:
+		return VPD(t, rh), nil
 	default:
 		return 0, nil
 	}
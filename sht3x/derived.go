@@ -0,0 +1,79 @@
+package sht3x
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// Channels derived from temperature and humidity are numbered after them
+const (
+	temperatureChannel = iota
+	humidityChannel
+	dewPointChannel
+	absoluteHumidityChannel
+	vpdChannel
+	channelCount
+)
+
+// _measurementAge is how old a temperature and humidity pair may get before
+// the sensor is measured again. Polling the five channels in turn then costs
+// one measurement, and the derived values agree with the raw ones.
+const _measurementAge = time.Second
+
+// Magnus formula coefficients over water, valid from -45°C to 60°C
+const (
+	magnusB = 17.62
+	magnusC = 243.12
+)
+
+// measurement holds the temperature and humidity the channels are computed from
+type measurement struct {
+	sync.Mutex
+	s      Sensor
+	temp   float64
+	rh     float64
+	readAt time.Time
+}
+
+func (r *measurement) get() (float64, float64, error) {
+	r.Lock()
+	defer r.Unlock()
+	if time.Since(r.readAt) < _measurementAge {
+		return r.temp, r.rh, nil
+	}
+	t, rh, err := r.s.ReadSensor()
+	if err != nil {
+		return 0, 0, err
+	}
+	r.temp, r.rh, r.readAt = t, rh, time.Now()
+	return t, rh, nil
+}
+
+// saturationPressure returns the saturation vapor pressure in hPa at t °C
+func saturationPressure(t float64) float64 {
+	return 6.112 * math.Exp(magnusB*t/(magnusC+t))
+}
+
+// DewPoint returns the temperature in °C at which air at t °C and rh %
+// relative humidity starts to condense.
+func DewPoint(t, rh float64) (float64, error) {
+	if rh <= 0 {
+		return 0, errors.New("dew point is undefined at 0% relative humidity")
+	}
+	g := math.Log(rh/100) + magnusB*t/(magnusC+t)
+	return magnusC * g / (magnusB - g), nil
+}
+
+// AbsoluteHumidity returns the water vapor content in g/m³ of air at t °C
+// and rh % relative humidity.
+func AbsoluteHumidity(t, rh float64) float64 {
+	return 216.7 * rh / 100 * saturationPressure(t) / (273.15 + t)
+}
+
+// VPD returns the vapor pressure deficit in kPa of air at t °C and rh %
+// relative humidity.
+func VPD(t, rh float64) float64 {
+	return saturationPressure(t) / 10 * (1 - rh/100)
+}
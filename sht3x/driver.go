@@ -6,28 +6,43 @@ import (
 	"github.com/reef-pi/rpi/i2c"
 )
 
+// Sensor is a temperature and humidity sensor of the SHT family
+type Sensor interface {
+	ReadSensor() (float64, float64, error)
+	Temperature() (float64, error)
+	Humidity() (float64, error)
+	Close() error
+}
+
 type Driver struct {
 	meta     hal.Metadata
+	sensor   Sensor
 	channels []hal.AnalogInputPin
 }
 
 func NewDriver(addr byte, bus i2c.Bus, meta hal.Metadata) (*Driver, error) {
-	s := &SHT31D{
+	return newDriver(&SHT31D{
 		addr: addr,
 		bus:  bus,
+	}, meta)
+}
+
+// newDriver exposes temperature, humidity and the channels derived from
+// them, all computed from the same reading
+func newDriver(s Sensor, meta hal.Metadata) (*Driver, error) {
+	r := &measurement{s: s}
+	d := &Driver{
+		meta:   meta,
+		sensor: s,
 	}
-	ch1, err := newChannel(s, 0)
-	if err != nil {
-		return nil, err
-	}
-	ch2, err := newChannel(s, 1)
-	if err != nil {
-		return nil, err
+	for i := 0; i < channelCount; i++ {
+		ch, err := newChannel(r, i)
+		if err != nil {
+			return nil, err
+		}
+		d.channels = append(d.channels, ch)
 	}
-	return &Driver{
-		meta:     meta,
-		channels: []hal.AnalogInputPin{ch1, ch2},
-	}, nil
+	return d, nil
 }
 
 func (d *Driver) Metadata() hal.Metadata {
@@ -36,7 +51,11 @@ func (d *Driver) Metadata() hal.Metadata {
 
 func (d *Driver) Pins(cap hal.Capability) ([]hal.Pin, error) {
 	if cap == hal.AnalogInput {
-		return []hal.Pin{d.channels[0], d.channels[1]}, nil
+		pins := make([]hal.Pin, len(d.channels))
+		for i, ch := range d.channels {
+			pins[i] = ch
+		}
+		return pins, nil
 	}
 	return nil, fmt.Errorf("unsupported capability: %s", cap.String())
 }
@@ -46,12 +65,17 @@ func (d *Driver) AnalogInputPins() []hal.AnalogInputPin {
 }
 
 func (d *Driver) AnalogInputPin(n int) (hal.AnalogInputPin, error) {
-	if n < 0 || n > 1 {
-		return nil, fmt.Errorf("sht31d board does not have channel %d", n)
+	if n < 0 || n >= len(d.channels) {
+		return nil, fmt.Errorf("%s board does not have channel %d", d.meta.Name, n)
 	}
 	return d.channels[n], nil
 }
 
+// Sensor returns the sensor behind the driver
+func (d *Driver) Sensor() Sensor {
+	return d.sensor
+}
+
 func (d *Driver) Close() error {
-	return nil
+	return d.sensor.Close()
 }
@@ -1,6 +1,7 @@
 package sht3x
 
 import (
+	"math"
 	"testing"
 
 	"github.com/reef-pi/hal"
@@ -39,10 +40,10 @@ func TestDriver(t *testing.T) {
 
 	d := driver.(hal.AnalogInputDriver)
 
-	if len(d.AnalogInputPins()) != 2 {
-		t.Error("Expected only one channel")
+	if len(d.AnalogInputPins()) != 5 {
+		t.Error("Expected temperature, humidity and three derived channels")
 	}
-	if _, err := d.AnalogInputPin(3); err == nil {
+	if _, err := d.AnalogInputPin(5); err == nil {
 		t.Error("Expected error for invalid channel name")
 	}
 
@@ -65,3 +66,55 @@ func TestDriver(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestDerivedChannels(t *testing.T) {
+	bus := newFakeBus()
+	bus.respond(CMD_SINGLE_MEASURE_HIGH, 0x6666, 0x8000)
+	driver, err := Factory().NewDriver(params, bus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pins, err := driver.Pins(hal.AnalogInput)
+	if err != nil || len(pins) != 5 {
+		t.Fatal("Expected 5 pins", err)
+	}
+	d := driver.(hal.AnalogInputDriver)
+	for i, c := range []struct {
+		name  string
+		value float64
+	}{
+		{"temperature", 25},
+		{"humidity", 50},
+		{"dew point", 13.85},
+		{"absolute humidity", 11.48},
+		{"vapor pressure deficit", 1.584},
+	} {
+		ch, err := d.AnalogInputPin(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ch.Name() != c.name {
+			t.Errorf("channel %d: expected %s, got %s", i, c.name, ch.Name())
+		}
+		v, err := ch.Value()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(v-c.value) > 0.01 {
+			t.Errorf("%s: expected %v, got %v", c.name, c.value, v)
+		}
+	}
+	if len(bus.commands) != 1 {
+		t.Error("all channels should share a single measurement, got", len(bus.commands))
+	}
+
+	if _, err := DewPoint(20, 0); err == nil {
+		t.Error("dew point should be undefined at 0% humidity")
+	}
+	if dp, _ := DewPoint(20, 100); math.Abs(dp-20) > 1e-9 {
+		t.Error("dew point at saturation should be the temperature, got", dp)
+	}
+	if vpd := VPD(20, 100); vpd != 0 {
+		t.Error("saturated air should have no vapor pressure deficit, got", vpd)
+	}
+}
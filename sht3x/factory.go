@@ -5,11 +5,16 @@ import (
 	"fmt"
 	"sync"
 
+	"github.com/reef-pi/drivers/internal/convert"
 	"github.com/reef-pi/hal"
 	"github.com/reef-pi/rpi/i2c"
 )
 
-const addressParam = "Address"
+const (
+	addressParam       = "Address"
+	periodicParam      = "Measurements Per Second"
+	repeatabilityParam = "Repeatability"
+)
 
 type factory struct {
 	meta       hal.Metadata
@@ -35,6 +40,18 @@ func Factory() hal.DriverFactory {
 					Order:   0,
 					Default: 0x44,
 				},
+				{
+					Name:    periodicParam,
+					Type:    hal.Decimal,
+					Order:   1,
+					Default: 0.0,
+				},
+				{
+					Name:    repeatabilityParam,
+					Type:    hal.String,
+					Order:   2,
+					Default: "high",
+				},
 			},
 		}
 	})
@@ -51,6 +68,29 @@ func (f *factory) GetParameters() []hal.ConfigParameter {
 
 func (f *factory) ValidateParameters(parameters map[string]interface{}) (bool, map[string][]string) {
 	var failures = make(map[string][]string)
+	validateAddress(parameters, failures)
+
+	if v, ok := parameters[periodicParam]; ok {
+		mps, ok := convert.ToFloat(v)
+		if !ok {
+			failure := fmt.Sprint(periodicParam, " is not a number. ", v, " was received.")
+			failures[periodicParam] = append(failures[periodicParam], failure)
+		} else if _, ok := periodicCommands[mps]; !ok && mps != 0 {
+			failure := fmt.Sprint(periodicParam, " is not a valid value of 0, 0.5, 1, 2, 4 or 10. ", v, " was received.")
+			failures[periodicParam] = append(failures[periodicParam], failure)
+		}
+	}
+
+	if v, ok := parameters[repeatabilityParam]; ok {
+		if _, err := parseRepeatability(v); err != nil {
+			failures[repeatabilityParam] = append(failures[repeatabilityParam], fmt.Sprint(repeatabilityParam, err.Error()))
+		}
+	}
+
+	return len(failures) == 0, failures
+}
+
+func validateAddress(parameters map[string]interface{}, failures map[string][]string) {
 	if v, ok := parameters[addressParam]; ok {
 		val, ok := hal.ConvertToInt(v)
 		if !ok {
@@ -65,8 +105,6 @@ func (f *factory) ValidateParameters(parameters map[string]interface{}) (bool, m
 		failure := fmt.Sprint(addressParam, " is a required parameter, but was not received.")
 		failures[addressParam] = append(failures[addressParam], failure)
 	}
-
-	return len(failures) == 0, failures
 }
 
 func (f *factory) NewDriver(parameters map[string]interface{}, hardwareResources interface{}) (hal.Driver, error) {
@@ -76,5 +114,18 @@ func (f *factory) NewDriver(parameters map[string]interface{}, hardwareResources
 	intAddress, _ := hal.ConvertToInt(parameters[addressParam])
 	address := byte(intAddress)
 	bus := hardwareResources.(i2c.Bus)
-	return NewDriver(address, bus, f.meta)
+	d, err := NewDriver(address, bus, f.meta)
+	if err != nil {
+		return nil, err
+	}
+	if mps, _ := convert.ToFloat(parameters[periodicParam]); mps != 0 {
+		r := RepeatabilityHigh
+		if v, ok := parameters[repeatabilityParam]; ok {
+			r, _ = parseRepeatability(v)
+		}
+		if err := d.sensor.(*SHT31D).StartPeriodic(mps, r); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
 }
@@ -0,0 +1,122 @@
+package sht3x
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"time"
+)
+
+// Repeatability trades measurement duration and power for lower noise
+type Repeatability int
+
+const (
+	RepeatabilityHigh Repeatability = iota
+	RepeatabilityMedium
+	RepeatabilityLow
+)
+
+var CMD_FETCH_DATA = []byte{0xE0, 0x00} // Read the latest measurement in periodic acquisition mode
+
+// periodicCommands holds the commands starting periodic acquisition, by
+// measurements per second and repeatability
+var periodicCommands = map[float64][3][]byte{
+	0.5: {{0x20, 0x32}, {0x20, 0x24}, {0x20, 0x2F}},
+	1:   {{0x21, 0x30}, {0x21, 0x26}, {0x21, 0x2D}},
+	2:   {{0x22, 0x36}, {0x22, 0x20}, {0x22, 0x2B}},
+	4:   {{0x23, 0x34}, {0x23, 0x22}, {0x23, 0x29}},
+	10:  {{0x27, 0x37}, {0x27, 0x21}, {0x27, 0x2A}},
+}
+
+// errNoAck is returned by Linux I2C adapters (EREMOTEIO) when a transfer is
+// not acknowledged
+const errNoAck = syscall.Errno(0x79)
+
+// _commandDelay is the time the sensor needs to process a command before
+// accepting the next one
+const _commandDelay = time.Millisecond
+
+func (r Repeatability) String() string {
+	switch r {
+	case RepeatabilityHigh:
+		return "high"
+	case RepeatabilityMedium:
+		return "medium"
+	case RepeatabilityLow:
+		return "low"
+	default:
+		return fmt.Sprintf("Repeatability(%d)", int(r))
+	}
+}
+
+func parseRepeatability(v interface{}) (Repeatability, error) {
+	s, ok := v.(string)
+	if !ok {
+		return 0, fmt.Errorf(" is not a string. %v was received.", v)
+	}
+	for _, r := range []Repeatability{RepeatabilityHigh, RepeatabilityMedium, RepeatabilityLow} {
+		if r.String() == s {
+			return r, nil
+		}
+	}
+	return 0, fmt.Errorf(" is not a valid value of high, medium or low. %v was received.", v)
+}
+
+func (d *SHT31D) command(cmd []byte) error {
+	if err := d.bus.WriteBytes(d.addr, cmd); err != nil {
+		return err
+	}
+	time.Sleep(_commandDelay)
+	return nil
+}
+
+// StartPeriodic starts periodic acquisition at mps measurements per second:
+// 0.5, 1, 2, 4 or 10. Readings then come from the latest measurement.
+func (d *SHT31D) StartPeriodic(mps float64, r Repeatability) error {
+	cmds, ok := periodicCommands[mps]
+	if !ok {
+		return fmt.Errorf("unsupported rate %v, expected 0.5, 1, 2, 4 or 10 measurements per second", mps)
+	}
+	if r < RepeatabilityHigh || r > RepeatabilityLow {
+		return fmt.Errorf("unsupported repeatability %v", r)
+	}
+	if err := d.command(cmds[r]); err != nil {
+		return err
+	}
+	d.periodic = true
+	d.interval = time.Duration(float64(time.Second) / mps)
+	return nil
+}
+
+// StopPeriodic stops periodic acquisition and returns to single shot mode
+func (d *SHT31D) StopPeriodic() error {
+	if err := d.command(CMD_BREAK); err != nil {
+		return err
+	}
+	d.periodic = false
+	return nil
+}
+
+// Periodic reports whether periodic acquisition is running
+func (d *SHT31D) Periodic() bool {
+	return d.periodic
+}
+
+// Fetch reads the latest measurement taken in periodic acquisition mode.
+// The sensor does not acknowledge the read when no new measurement is
+// available, in which case the previous measurement is returned as long as
+// it is no older than two measurement intervals. Other errors, including CRC
+// mismatches, are returned.
+func (d *SHT31D) Fetch() (float64, float64, error) {
+	if !d.periodic {
+		return 0, 0, fmt.Errorf("periodic acquisition is not running")
+	}
+	if err := d.bus.WriteBytes(d.addr, CMD_FETCH_DATA); err != nil {
+		return 0, 0, err
+	}
+	temp, rh, err := d.measurement()
+	if errors.Is(err, errNoAck) && time.Since(d.pTime) < 2*d.interval {
+		return d.pTemp, d.pHumidity, nil
+	}
+	return temp, rh, err
+}
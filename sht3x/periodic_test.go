@@ -0,0 +1,189 @@
+package sht3x
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+// fakeBus answers reads with the response to the last command written
+type fakeBus struct {
+	commands  [][]byte
+	responses map[string][]uint16
+	readErr   error
+	corrupt   bool
+}
+
+func newFakeBus() *fakeBus { return &fakeBus{responses: make(map[string][]uint16)} }
+
+func (b *fakeBus) respond(cmd []byte, words ...uint16) {
+	b.responses[string(cmd)] = words
+}
+
+func (b *fakeBus) last() []byte {
+	if len(b.commands) == 0 {
+		return nil
+	}
+	return b.commands[len(b.commands)-1]
+}
+
+func (b *fakeBus) SetAddress(_ byte) error { return nil }
+func (b *fakeBus) ReadBytes(_ byte, n int) ([]byte, error) {
+	if b.readErr != nil {
+		return nil, b.readErr
+	}
+	var data []byte
+	if cmd := b.last(); cmd != nil {
+		for _, w := range b.responses[string(cmd)] {
+			word := []byte{byte(w >> 8), byte(w)}
+			c := crc(0xFF, word)
+			if b.corrupt {
+				c = ^c
+			}
+			data = append(data, word[0], word[1], c)
+		}
+	}
+	return append(data, make([]byte, n)...)[:n], nil
+}
+func (b *fakeBus) WriteBytes(_ byte, v []byte) error {
+	b.commands = append(b.commands, append([]byte(nil), v...))
+	return nil
+}
+func (b *fakeBus) ReadFromReg(_, _ byte, _ []byte) error { return nil }
+func (b *fakeBus) WriteToReg(_, _ byte, _ []byte) error  { return nil }
+func (b *fakeBus) Close() error                          { return nil }
+
+func TestPeriodic(t *testing.T) {
+	bus := newFakeBus()
+	bus.respond(CMD_FETCH_DATA, 0x6666, 0x8000)
+	p := map[string]interface{}{
+		"Address":                 0x44,
+		"Measurements Per Second": 2,
+		"Repeatability":           "medium",
+	}
+	f := Factory()
+	if valid, failures := f.ValidateParameters(p); !valid {
+		t.Fatal(failures)
+	}
+	driver, err := f.NewDriver(p, bus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(bus.last(), []byte{0x22, 0x20}) {
+		t.Errorf("expected periodic command 0x2220, got %x", bus.last())
+	}
+	s := driver.(*Driver).Sensor().(*SHT31D)
+	temp, rh, err := s.ReadSensor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(bus.last(), CMD_FETCH_DATA) {
+		t.Errorf("periodic readings should fetch data, got %x", bus.last())
+	}
+	if temp < 24.99 || temp > 25.01 || rh < 50 || rh > 50.01 {
+		t.Error("unexpected reading", temp, rh)
+	}
+
+	if err := driver.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(bus.last(), CMD_BREAK) || s.Periodic() {
+		t.Error("closing should stop periodic acquisition")
+	}
+	if _, _, err := s.Fetch(); err == nil {
+		t.Error("fetching outside periodic mode should fail")
+	}
+	if err := s.StartPeriodic(3, RepeatabilityHigh); err == nil {
+		t.Error("3 measurements per second is not supported")
+	}
+
+	for param, v := range map[string]interface{}{"Measurements Per Second": 5, "Repeatability": "best"} {
+		if valid, _ := f.ValidateParameters(map[string]interface{}{"Address": 0x44, param: v}); valid {
+			t.Errorf("%s %v should be rejected", param, v)
+		}
+	}
+}
+
+func TestHeaterAndStatus(t *testing.T) {
+	bus := newFakeBus()
+	driver, err := Factory().NewDriver(params, bus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := driver.(*Driver).Sensor().(*SHT31D)
+	if err := s.SetHeater(true); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(bus.last(), CMD_HEATER_ON) {
+		t.Errorf("expected heater on command, got %x", bus.last())
+	}
+	if err := s.SetHeater(false); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(bus.last(), CMD_HEATER_OFF) {
+		t.Errorf("expected heater off command, got %x", bus.last())
+	}
+
+	bus.respond(CMD_READ_STATUS, 0xA813)
+	st, err := s.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Status{
+		AlertPending:   true,
+		HeaterOn:       true,
+		HumidityAlert:  true,
+		ResetDetected:  true,
+		CommandFailed:  true,
+		ChecksumFailed: true,
+		Raw:            0xA813,
+	}
+	if st != want {
+		t.Errorf("expected %+v, got %+v", want, st)
+	}
+	if err := s.ClearStatus(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(bus.last(), CMD_CLEAR_STATUS) {
+		t.Errorf("expected clear status command, got %x", bus.last())
+	}
+}
+
+func TestFetchWithoutNewMeasurement(t *testing.T) {
+	bus := newFakeBus()
+	bus.respond(CMD_FETCH_DATA, 0x6666, 0x8000)
+	s := &SHT31D{addr: 0x44, bus: bus}
+	if err := s.StartPeriodic(0.5, RepeatabilityHigh); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.Fetch(); err != nil {
+		t.Fatal(err)
+	}
+	// the sensor does not acknowledge the read until the next measurement
+	bus.readErr = &os.PathError{Op: "read", Path: "/dev/i2c-1", Err: errNoAck}
+	temp, rh, err := s.ReadSensor()
+	if err != nil {
+		t.Fatal("expected the previous measurement, got", err)
+	}
+	if temp < 24.99 || temp > 25.01 || rh < 50 || rh > 50.01 {
+		t.Error("unexpected reading", temp, rh)
+	}
+	// bus faults and corrupted measurements are not hidden
+	bus.readErr = errors.New("i2c: Unexpected number (0) of bytes read")
+	if _, _, err := s.ReadSensor(); err == nil {
+		t.Error("expected a bus fault to be returned")
+	}
+	bus.readErr = nil
+	bus.corrupt = true
+	if _, _, err := s.ReadSensor(); err == nil {
+		t.Error("expected a CRC mismatch to be returned")
+	}
+	bus.corrupt = false
+	bus.readErr = &os.PathError{Op: "read", Path: "/dev/i2c-1", Err: errNoAck}
+	s.pTime = time.Now().Add(-5 * time.Second)
+	if _, _, err := s.ReadSensor(); err == nil {
+		t.Error("expected an error once the previous measurement is stale")
+	}
+}
@@ -0,0 +1,142 @@
+package sht3x
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/reef-pi/hal"
+	"github.com/reef-pi/rpi/i2c"
+)
+
+const (
+	precisionParam = "Precision"
+	lowPowerParam  = "Low Power"
+)
+
+// sensorFactory creates drivers for sensors configured by their address and
+// one sensor specific option
+type sensorFactory struct {
+	meta       hal.Metadata
+	parameters []hal.ConfigParameter
+	// parse validates the option, absent options are passed as nil
+	parse func(interface{}) (interface{}, error)
+	// sensor returns the sensor for a validated address and parsed option
+	sensor func(addr byte, bus i2c.Bus, option interface{}) Sensor
+}
+
+var sht4xF, shtc3F *sensorFactory
+var sht4xOnce, shtc3Once sync.Once
+
+// SHT4xFactory returns a singleton SHT40/SHT41/SHT45 Driver factory
+func SHT4xFactory() hal.DriverFactory {
+	sht4xOnce.Do(func() {
+		sht4xF = &sensorFactory{
+			meta: hal.Metadata{
+				Name:         "sht4x",
+				Description:  "SHT40, SHT41 and SHT45 humidity and temperature sensors",
+				Capabilities: []hal.Capability{hal.AnalogInput},
+			},
+			parameters: []hal.ConfigParameter{
+				{
+					Name:    addressParam,
+					Type:    hal.Integer,
+					Order:   0,
+					Default: 0x44,
+				},
+				{
+					Name:    precisionParam,
+					Type:    hal.String,
+					Order:   1,
+					Default: "high",
+				},
+			},
+			parse: func(v interface{}) (interface{}, error) {
+				if v == nil {
+					return RepeatabilityHigh, nil
+				}
+				return parseRepeatability(v)
+			},
+			sensor: func(addr byte, bus i2c.Bus, option interface{}) Sensor {
+				return NewSHT4x(addr, bus, option.(Repeatability))
+			},
+		}
+	})
+	return sht4xF
+}
+
+// SHTC3Factory returns a singleton SHTC3 Driver factory
+func SHTC3Factory() hal.DriverFactory {
+	shtc3Once.Do(func() {
+		shtc3F = &sensorFactory{
+			meta: hal.Metadata{
+				Name:         "shtc3",
+				Description:  "SHTC3 humidity and temperature sensor",
+				Capabilities: []hal.Capability{hal.AnalogInput},
+			},
+			parameters: []hal.ConfigParameter{
+				{
+					Name:    addressParam,
+					Type:    hal.Integer,
+					Order:   0,
+					Default: 0x70,
+				},
+				{
+					Name:    lowPowerParam,
+					Type:    hal.Boolean,
+					Order:   1,
+					Default: false,
+				},
+			},
+			parse: func(v interface{}) (interface{}, error) {
+				if v == nil {
+					return false, nil
+				}
+				b, ok := v.(bool)
+				if !ok {
+					return nil, fmt.Errorf(" is not a boolean. %v was received.", v)
+				}
+				return b, nil
+			},
+			sensor: func(addr byte, bus i2c.Bus, option interface{}) Sensor {
+				return NewSHTC3(addr, bus, option.(bool))
+			},
+		}
+	})
+	return shtc3F
+}
+
+func (f *sensorFactory) Metadata() hal.Metadata {
+	return f.meta
+}
+
+func (f *sensorFactory) GetParameters() []hal.ConfigParameter {
+	return f.parameters
+}
+
+// optionParam is the name of the sensor specific option
+func (f *sensorFactory) optionParam() string {
+	return f.parameters[1].Name
+}
+
+func (f *sensorFactory) ValidateParameters(parameters map[string]interface{}) (bool, map[string][]string) {
+	var failures = make(map[string][]string)
+	validateAddress(parameters, failures)
+
+	param := f.optionParam()
+	if _, err := f.parse(parameters[param]); err != nil {
+		failures[param] = append(failures[param], fmt.Sprint(param, err.Error()))
+	}
+
+	return len(failures) == 0, failures
+}
+
+func (f *sensorFactory) NewDriver(parameters map[string]interface{}, hardwareResources interface{}) (hal.Driver, error) {
+	if valid, failures := f.ValidateParameters(parameters); !valid {
+		return nil, errors.New(hal.ToErrorString(failures))
+	}
+	intAddress, _ := hal.ConvertToInt(parameters[addressParam])
+	option, _ := f.parse(parameters[f.optionParam()])
+	bus := hardwareResources.(i2c.Bus)
+	return newDriver(f.sensor(byte(intAddress), bus, option), f.meta)
+}
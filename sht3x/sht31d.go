@@ -30,11 +30,19 @@ type SHT31D struct {
 	bus              i2c.Bus
 	pTemp, pHumidity float64
 	pTime            time.Time
+	periodic         bool
+	interval         time.Duration // between measurements in periodic mode
 }
 
 func (d *SHT31D) read(blockCount int) ([]uint16, error) {
+	return readWords(d.bus, d.addr, blockCount)
+}
+
+// readWords reads blockCount 16 bit words, each followed by its CRC-8, as
+// sent by every sensor of the family
+func readWords(bus i2c.Bus, addr byte, blockCount int) ([]uint16, error) {
 	const blockSize = 2 + 1
-	data, err := d.bus.ReadBytes(d.addr, blockCount*blockSize)
+	data, err := bus.ReadBytes(addr, blockCount*blockSize)
 	if err != nil {
 		return nil, err
 	}
@@ -64,10 +72,27 @@ func (d *SHT31D) initiateMeasure(cmd []byte) error {
 	return nil
 }
 
+// Close returns the sensor to single shot mode
+func (d *SHT31D) Close() error {
+	if d.periodic {
+		return d.StopPeriodic()
+	}
+	return nil
+}
+
+// ReadSensor returns the temperature and relative humidity, from a single
+// shot measurement or the latest one in periodic acquisition mode.
 func (d *SHT31D) ReadSensor() (float64, float64, error) {
+	if d.periodic {
+		return d.Fetch()
+	}
 	if err := d.initiateMeasure(CMD_SINGLE_MEASURE_HIGH); err != nil {
 		return 0, 0, err
 	}
+	return d.measurement()
+}
+
+func (d *SHT31D) measurement() (float64, float64, error) {
 	data, err := d.read(2)
 	if err != nil {
 		return 0, 0, err
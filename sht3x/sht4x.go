@@ -0,0 +1,144 @@
+package sht3x
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/reef-pi/rpi/i2c"
+)
+
+// HeaterPower selects the power of an SHT4x heater pulse
+type HeaterPower int
+
+const (
+	Heater200mW HeaterPower = iota
+	Heater110mW
+	Heater20mW
+)
+
+// SHT4x measurement commands and their maximum duration, by precision
+var sht4xMeasures = [3]struct {
+	cmd   byte
+	delay time.Duration
+}{
+	{0xFD, 8300 * time.Microsecond},
+	{0xF6, 4500 * time.Microsecond},
+	{0xE0, 1700 * time.Microsecond},
+}
+
+// SHT4x heater commands by power, for 1s and 0.1s pulses
+var sht4xHeaters = [3][2]byte{
+	{0x39, 0x32},
+	{0x2F, 0x24},
+	{0x1E, 0x15},
+}
+
+const (
+	sht4xSerialNumber = 0x89
+	sht4xSoftReset    = 0x94
+)
+
+// SHT4x drives the SHT40, SHT41 and SHT45 sensors, which take single byte
+// commands.
+type SHT4x struct {
+	addr      byte
+	bus       i2c.Bus
+	precision Repeatability
+}
+
+// NewSHT4x returns an SHT4x measuring at the given precision
+func NewSHT4x(addr byte, bus i2c.Bus, precision Repeatability) *SHT4x {
+	return &SHT4x{
+		addr:      addr,
+		bus:       bus,
+		precision: precision,
+	}
+}
+
+func (d *SHT4x) ReadSensor() (float64, float64, error) {
+	if d.precision < RepeatabilityHigh || d.precision > RepeatabilityLow {
+		return 0, 0, fmt.Errorf("unsupported precision %v", d.precision)
+	}
+	m := sht4xMeasures[d.precision]
+	if err := d.bus.WriteBytes(d.addr, []byte{m.cmd}); err != nil {
+		return 0, 0, err
+	}
+	time.Sleep(m.delay)
+	return d.measurement()
+}
+
+// HeaterPulse heats the sensor for 1s, or 0.1s when long is false, then
+// returns the high precision measurement taken at the end of the pulse.
+func (d *SHT4x) HeaterPulse(power HeaterPower, long bool) (float64, float64, error) {
+	if power < Heater200mW || power > Heater20mW {
+		return 0, 0, fmt.Errorf("unsupported heater power %d", power)
+	}
+	cmd, delay := sht4xHeaters[power][1], 110*time.Millisecond
+	if long {
+		cmd, delay = sht4xHeaters[power][0], 1100*time.Millisecond
+	}
+	if err := d.bus.WriteBytes(d.addr, []byte{cmd}); err != nil {
+		return 0, 0, err
+	}
+	time.Sleep(delay)
+	return d.measurement()
+}
+
+// SerialNumber reads the unique serial number of the sensor
+func (d *SHT4x) SerialNumber() (uint32, error) {
+	if err := d.bus.WriteBytes(d.addr, []byte{sht4xSerialNumber}); err != nil {
+		return 0, err
+	}
+	time.Sleep(_commandDelay)
+	data, err := readWords(d.bus, d.addr, 2)
+	if err != nil {
+		return 0, err
+	}
+	return uint32(data[0])<<16 | uint32(data[1]), nil
+}
+
+// Reset performs a soft reset
+func (d *SHT4x) Reset() error {
+	if err := d.bus.WriteBytes(d.addr, []byte{sht4xSoftReset}); err != nil {
+		return err
+	}
+	time.Sleep(_commandDelay)
+	return nil
+}
+
+func (d *SHT4x) measurement() (float64, float64, error) {
+	data, err := readWords(d.bus, d.addr, 2)
+	if err != nil {
+		return 0, 0, err
+	}
+	temp := float64(data[0])*175/(0x10000-1) - 45
+	rh := float64(data[1])*125/(0x10000-1) - 6
+	return temp, clampHumidity(rh), nil
+}
+
+func (d *SHT4x) Temperature() (float64, error) {
+	t, _, err := d.ReadSensor()
+	return t, err
+}
+
+func (d *SHT4x) Humidity() (float64, error) {
+	_, rh, err := d.ReadSensor()
+	return rh, err
+}
+
+func (d *SHT4x) Close() error {
+	return nil
+}
+
+// clampHumidity limits relative humidity to 0-100, as the conversion of
+// some sensors extends slightly past the physical range
+func clampHumidity(rh float64) float64 {
+	switch {
+	case rh < 0:
+		return 0
+	case rh > 100:
+		return 100
+	default:
+		return rh
+	}
+}
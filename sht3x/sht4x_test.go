@@ -0,0 +1,67 @@
+package sht3x
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/reef-pi/hal"
+)
+
+func TestSHT4x(t *testing.T) {
+	bus := newFakeBus()
+	bus.respond([]byte{0xF6}, 0x6666, 0x8000)
+	bus.respond([]byte{0x15}, 0x8000, 0xFFFF)
+	bus.respond([]byte{0x89}, 0x1234, 0x5678)
+	p := map[string]interface{}{
+		"Address":   0x45,
+		"Precision": "medium",
+	}
+	f := SHT4xFactory()
+	if valid, failures := f.ValidateParameters(p); !valid {
+		t.Fatal(failures)
+	}
+	driver, err := f.NewDriver(p, bus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if driver.Metadata().Name != "sht4x" {
+		t.Error("Unexpected name")
+	}
+	d := driver.(hal.AnalogInputDriver)
+	ch, err := d.AnalogInputPin(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ch.Name() != "humidity" {
+		t.Error("Unexpected channel name")
+	}
+	rh, err := ch.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rh < 56.5 || rh > 56.51 {
+		t.Error("Unexpected humidity:", rh)
+	}
+	if !bytes.Equal(bus.last(), []byte{0xF6}) {
+		t.Errorf("expected medium precision command, got %x", bus.last())
+	}
+
+	s := driver.(*Driver).Sensor().(*SHT4x)
+	temp, rh, err := s.HeaterPulse(Heater20mW, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if temp < 42.5 || temp > 42.51 || rh != 100 {
+		t.Error("Unexpected heater pulse reading", temp, rh)
+	}
+	sn, err := s.SerialNumber()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sn != 0x12345678 {
+		t.Errorf("Unexpected serial number %x", sn)
+	}
+	if valid, _ := f.ValidateParameters(map[string]interface{}{"Address": 0x44, "Precision": "ultra"}); valid {
+		t.Error("unknown precision should be rejected")
+	}
+}
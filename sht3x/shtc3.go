@@ -0,0 +1,108 @@
+package sht3x
+
+import (
+	"time"
+
+	"github.com/reef-pi/rpi/i2c"
+)
+
+var (
+	CMD_SHTC3_WAKEUP    = []byte{0x35, 0x17} // Wake the sensor from sleep
+	CMD_SHTC3_SLEEP     = []byte{0xB0, 0x98} // Enter sleep mode
+	CMD_SHTC3_RESET     = []byte{0x80, 0x5D} // Soft reset
+	CMD_SHTC3_ID        = []byte{0xEF, 0xC8} // Read the ID register
+	CMD_SHTC3_MEASURE   = []byte{0x78, 0x66} // Measure temperature first, normal mode
+	CMD_SHTC3_MEASURE_L = []byte{0x60, 0x9C} // Measure temperature first, low power mode
+)
+
+const (
+	_shtc3WakeupDelay   = 240 * time.Microsecond
+	_shtc3Delay         = 12100 * time.Microsecond
+	_shtc3LowPowerDelay = 800 * time.Microsecond
+)
+
+// SHTC3 drives the SHTC3 sensor. It sleeps between measurements, so every
+// reading wakes it first.
+type SHTC3 struct {
+	addr     byte
+	bus      i2c.Bus
+	lowPower bool
+}
+
+// NewSHTC3 returns an SHTC3, measuring in low power mode when lowPower is set
+func NewSHTC3(addr byte, bus i2c.Bus, lowPower bool) *SHTC3 {
+	return &SHTC3{
+		addr:     addr,
+		bus:      bus,
+		lowPower: lowPower,
+	}
+}
+
+// Wakeup brings the sensor out of sleep mode
+func (d *SHTC3) Wakeup() error {
+	if err := d.bus.WriteBytes(d.addr, CMD_SHTC3_WAKEUP); err != nil {
+		return err
+	}
+	time.Sleep(_shtc3WakeupDelay)
+	return nil
+}
+
+// Sleep puts the sensor in sleep mode until the next Wakeup
+func (d *SHTC3) Sleep() error {
+	return d.bus.WriteBytes(d.addr, CMD_SHTC3_SLEEP)
+}
+
+// ID reads the ID register of the sensor
+func (d *SHTC3) ID() (uint16, error) {
+	if err := d.Wakeup(); err != nil {
+		return 0, err
+	}
+	defer d.Sleep()
+	if err := d.bus.WriteBytes(d.addr, CMD_SHTC3_ID); err != nil {
+		return 0, err
+	}
+	data, err := readWords(d.bus, d.addr, 1)
+	if err != nil {
+		return 0, err
+	}
+	return data[0], nil
+}
+
+// ReadSensor wakes the sensor up for a measurement and sends it back to
+// sleep, also when the measurement fails.
+func (d *SHTC3) ReadSensor() (float64, float64, error) {
+	if err := d.Wakeup(); err != nil {
+		return 0, 0, err
+	}
+	defer d.Sleep()
+	cmd, delay := CMD_SHTC3_MEASURE, _shtc3Delay
+	if d.lowPower {
+		cmd, delay = CMD_SHTC3_MEASURE_L, _shtc3LowPowerDelay
+	}
+	if err := d.bus.WriteBytes(d.addr, cmd); err != nil {
+		return 0, 0, err
+	}
+	time.Sleep(delay)
+	data, err := readWords(d.bus, d.addr, 2)
+	if err != nil {
+		return 0, 0, err
+	}
+	temp := float64(data[0])*175/0x10000 - 45
+	rh := float64(data[1]) * 100 / 0x10000
+	return temp, rh, nil
+}
+
+func (d *SHTC3) Temperature() (float64, error) {
+	t, _, err := d.ReadSensor()
+	return t, err
+}
+
+func (d *SHTC3) Humidity() (float64, error) {
+	_, rh, err := d.ReadSensor()
+	return rh, err
+}
+
+// Close leaves the sensor asleep
+func (d *SHTC3) Close() error {
+	return d.Sleep()
+}
@@ -0,0 +1,66 @@
+package sht3x
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/reef-pi/hal"
+)
+
+func TestSHTC3(t *testing.T) {
+	bus := newFakeBus()
+	bus.respond(CMD_SHTC3_MEASURE_L, 0x6666, 0x8000)
+	p := map[string]interface{}{
+		"Address":   0x70,
+		"Low Power": true,
+	}
+	f := SHTC3Factory()
+	if valid, failures := f.ValidateParameters(p); !valid {
+		t.Fatal(failures)
+	}
+	driver, err := f.NewDriver(p, bus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if driver.Metadata().Name != "shtc3" {
+		t.Error("Unexpected name")
+	}
+	ch, err := driver.(hal.AnalogInputDriver).AnalogInputPin(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus.commands = nil
+	temp, err := ch.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if temp < 24.99 || temp > 25.01 {
+		t.Error("Unexpected temperature:", temp)
+	}
+	want := [][]byte{CMD_SHTC3_WAKEUP, CMD_SHTC3_MEASURE_L, CMD_SHTC3_SLEEP}
+	if len(bus.commands) != len(want) {
+		t.Fatalf("expected wakeup, measure and sleep, got %x", bus.commands)
+	}
+	for i, cmd := range want {
+		if !bytes.Equal(bus.commands[i], cmd) {
+			t.Errorf("command %d: expected %x, got %x", i, cmd, bus.commands[i])
+		}
+	}
+
+	// A failed measurement still sends the sensor back to sleep
+	bus.corrupt = true
+	if _, _, err := NewSHTC3(0x70, bus, true).ReadSensor(); err == nil {
+		t.Error("expected a CRC error")
+	}
+	if !bytes.Equal(bus.last(), CMD_SHTC3_SLEEP) {
+		t.Error("a failed measurement should leave the sensor asleep")
+	}
+	bus.corrupt = false
+
+	if err := driver.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(bus.last(), CMD_SHTC3_SLEEP) {
+		t.Error("closing should leave the sensor asleep")
+	}
+}
@@ -0,0 +1,59 @@
+package sht3x
+
+var (
+	CMD_HEATER_ON    = []byte{0x30, 0x6D} // Enable the on-chip heater
+	CMD_HEATER_OFF   = []byte{0x30, 0x66} // Disable the on-chip heater
+	CMD_READ_STATUS  = []byte{0xF3, 0x2D} // Read the status register
+	CMD_CLEAR_STATUS = []byte{0x30, 0x41} // Clear the alert and reset flags of the status register
+)
+
+// Status is the parsed status register
+type Status struct {
+	AlertPending     bool // at least one pending alert
+	HeaterOn         bool
+	HumidityAlert    bool // humidity tracking alert
+	TemperatureAlert bool // temperature tracking alert
+	ResetDetected    bool // reset since the last status clear
+	CommandFailed    bool // last command was not processed
+	ChecksumFailed   bool // checksum of the last write transfer failed
+	Raw              uint16
+}
+
+func parseStatus(v uint16) Status {
+	return Status{
+		AlertPending:     v&(1<<15) != 0,
+		HeaterOn:         v&(1<<13) != 0,
+		HumidityAlert:    v&(1<<11) != 0,
+		TemperatureAlert: v&(1<<10) != 0,
+		ResetDetected:    v&(1<<4) != 0,
+		CommandFailed:    v&(1<<1) != 0,
+		ChecksumFailed:   v&(1<<0) != 0,
+		Raw:              v,
+	}
+}
+
+// SetHeater turns the on-chip heater on or off. Heating clears condensation
+// from the sensor but raises the temperature it reads.
+func (d *SHT31D) SetHeater(on bool) error {
+	if on {
+		return d.command(CMD_HEATER_ON)
+	}
+	return d.command(CMD_HEATER_OFF)
+}
+
+// Status reads the status register
+func (d *SHT31D) Status() (Status, error) {
+	if err := d.bus.WriteBytes(d.addr, CMD_READ_STATUS); err != nil {
+		return Status{}, err
+	}
+	data, err := d.read(1)
+	if err != nil {
+		return Status{}, err
+	}
+	return parseStatus(data[0]), nil
+}
+
+// ClearStatus clears the alert and reset detected flags
+func (d *SHT31D) ClearStatus() error {
+	return d.command(CMD_CLEAR_STATUS)
+}